@@ -9,9 +9,26 @@ import (
 	"monte/heap"
 )
 
+// MaxSize is the largest width and height of a board. The stones and scores
+// are kept in arrays of that size so that a board is copied and compared as a
+// plain value whatever its dimensions.
+const MaxSize = 19
+
 type Board struct {
-	stones [Size][Size]Stone
-	scores [Size][Size]Score
+	stones  [MaxSize][MaxSize]Stone
+	scores  [MaxSize][MaxSize]Score
+	hash    uint64
+	width   int
+	height  int
+	scoring *Scoring
+	placed  int
+	winner  Stone
+	line    line
+}
+
+// line is the winning row of n stones starting at x, y in direction dx, dy.
+type line struct {
+	x, y, dx, dy, n int8
 }
 
 type Place struct {
@@ -44,32 +61,80 @@ func (stone Stone) String() string {
 	return "None"
 }
 
-const maxStones1 = maxStones - 1
+// rowDirections are the directions of the rows of places.
+var rowDirections = [4][2]int{{1, 0}, {0, 1}, {1, 1}, {-1, 1}}
 
-func MakeBoard() Board {
-	board := Board{}
-	for y := 0; y < Size; y++ {
-		v := 1 + min(maxStones1, y, Size-1-y)
-		for x := 0; x < Size; x++ {
-			h := 1 + min(maxStones1, x, Size-1-x)
-			m := 1 + min(x, y, Size-1-x, Size-1-y)
-			t1 := max(0, min(maxStones, m, Size-maxStones1-y+x, Size-maxStones1-x+y))
-			t2 := max(0, min(maxStones, m, 2*Size-1-maxStones1-y-x, x+y-maxStones1+1))
-			total := Score(v + h + t1 + t2)
+func MakeBoard(width, height int, scoring *Scoring) Board {
+	if width < 1 || width > MaxSize || height < 1 || height > MaxSize {
+		panic(fmt.Sprintf("MakeBoard(%d, %d): unsupported size", width, height))
+	}
+	board := Board{width: width, height: height, scoring: scoring}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			total := Score(0)
+			for _, d := range rowDirections {
+				_, _, n := board.rows(x, y, d[0], d[1])
+				total += Score(n) * scoring.Values[0]
+			}
 			board.scores[y][x] = total
 		}
 	}
 	return board
 }
 
+func (b *Board) Width() int {
+	return b.width
+}
+
+func (b *Board) Height() int {
+	return b.height
+}
+
+func (b *Board) Scoring() *Scoring {
+	return b.scoring
+}
+
+func (b *Board) Contains(x, y int) bool {
+	return x >= 0 && x < b.width && y >= 0 && y < b.height
+}
+
+// rows returns the first place and the number of the rows of places in
+// direction dx, dy that contain the place x, y.
+func (b *Board) rows(x, y, dx, dy int) (int, int, int) {
+	row1 := b.scoring.Row - 1
+	back := min(row1, reach(x, -dx, b.width), reach(y, -dy, b.height))
+	forth := min(row1, reach(x, dx, b.width), reach(y, dy, b.height))
+	return x - back*dx, y - back*dy, max(0, back+forth+1-row1)
+}
+
+// reach returns how many steps d can be made from v within size.
+func reach(v, d, size int) int {
+	switch d {
+	case 1:
+		return size - 1 - v
+	case -1:
+		return v
+	}
+	return MaxSize
+}
+
+func (b *Board) Stone(x, y int8) Stone {
+	return b.stones[y][x]
+}
+
 func (b *Board) Score(x, y int8) int16 {
 	return int16(b.scores[y][x])
 }
 
+// Hash returns the Zobrist hash of the stones on the board.
+func (b *Board) Hash() uint64 {
+	return b.hash
+}
+
 func (b *Board) TopPlaces(places *[]Place) {
 	*places = (*places)[:0]
-	for y := int8(0); y < Size; y++ {
-		for x := int8(0); x < Size; x++ {
+	for y := int8(0); y < int8(b.height); y++ {
+		for x := int8(0); x < int8(b.width); x++ {
 			if b.stones[y][x] != None {
 				continue
 			}
@@ -78,74 +143,132 @@ func (b *Board) TopPlaces(places *[]Place) {
 	}
 }
 
+// PlaceStone places a stone on an empty place and reports whether it wins. A
+// winning stone or a stone on the last empty place ends the game; placing
+// stones after that panics.
 func (b *Board) PlaceStone(turn Turn, x, y int) bool {
-	{
-		start := max(0, x-maxStones1)
-		end := min(x+maxStones, Size) - maxStones1
-		n := end - start
-		if b.updateRow(turn, start, y, 1, 0, n) {
-			return true
+	if b.IsOver() {
+		panic(fmt.Sprintf("PlaceStone(%v, %d, %d): game over", turn, x, y))
+	}
+	if b.stones[y][x] != None {
+		panic(fmt.Sprintf("PlaceStone(%v, %d, %d): place occupied", turn, x, y))
+	}
+	stone := Black
+	if turn == Second {
+		stone = White
+	}
+	line, wins := b.winningLine(stone, x, y)
+
+	b.updateScores(turn, x, y, 1)
+	b.stones[y][x] = stone
+	b.hash ^= zobrist[y][x][turn]
+	b.placed++
+	if wins {
+		b.winner, b.line = stone, line
+	}
+	b.validate()
+	return wins
+}
+
+// RemoveStone takes back a stone placed by PlaceStone, restoring the scores
+// and the game if the stone ended it. Removing from an empty place panics.
+func (b *Board) RemoveStone(x, y int) {
+	if b.stones[y][x] == None {
+		panic(fmt.Sprintf("RemoveStone(%d, %d): place empty", x, y))
+	}
+	turn := First
+	if b.stones[y][x] == White {
+		turn = Second
+	}
+	b.stones[y][x] = None
+	b.hash ^= zobrist[y][x][turn]
+	b.placed--
+	if b.winner != None && b.line.contains(x, y) {
+		b.winner, b.line = None, line{}
+	}
+	b.updateScores(turn, x, y, -1)
+	b.validate()
+}
+
+func (b *Board) winningLine(stone Stone, x, y int) (line, bool) {
+	for _, d := range rowDirections {
+		dx, dy := d[0], d[1]
+		n := 1
+		for xx, yy := x+dx, y+dy; b.Contains(xx, yy) && b.stones[yy][xx] == stone; xx, yy = xx+dx, yy+dy {
+			n++
+		}
+		back := 0
+		for xx, yy := x-dx, y-dy; b.Contains(xx, yy) && b.stones[yy][xx] == stone; xx, yy = xx-dx, yy-dy {
+			back++
+		}
+		if b.scoring.wins(stone, n+back) {
+			return line{int8(x - back*dx), int8(y - back*dy), int8(dx), int8(dy), int8(n + back)}, true
 		}
 	}
+	return line{}, false
+}
 
-	{
-		start := max(0, y-maxStones1)
-		end := min(y+maxStones, Size) - maxStones1
-		n := end - start
-		if b.updateRow(turn, x, start, 0, 1, n) {
+func (l line) contains(x, y int) bool {
+	for i := range int(l.n) {
+		if int(l.x)+i*int(l.dx) == x && int(l.y)+i*int(l.dy) == y {
 			return true
 		}
 	}
+	return false
+}
 
-	m := 1 + min(x, y, Size-1-x, Size-1-y)
+// IsOver reports whether a stone won or the board is full.
+func (b *Board) IsOver() bool {
+	return b.winner != None || b.placed == b.width*b.height
+}
 
-	{
-		n := min(maxStones, m, Size-maxStones1-y+x, Size-maxStones1-x+y)
-		if n > 0 {
-			mn := min(x, y, maxStones1)
-			xStart := x - mn
-			yStart := y - mn
-			if b.updateRow(turn, xStart, yStart, 1, 1, n) {
-				return true
-			}
-		}
+// IsDraw reports whether the board is full without a winner.
+func (b *Board) IsDraw() bool {
+	return b.winner == None && b.placed == b.width*b.height
+}
+
+// Winner returns the stone of the winner, or None.
+func (b *Board) Winner() Stone {
+	return b.winner
+}
+
+// WinningLine returns the places of the winning stones, or nil. The scores of
+// the places are not set.
+func (b *Board) WinningLine() []Place {
+	if b.winner == None {
+		return nil
 	}
+	places := make([]Place, b.line.n)
+	for i := range places {
+		places[i] = Place{X: b.line.x + int8(i)*b.line.dx, Y: b.line.y + int8(i)*b.line.dy}
+	}
+	return places
+}
 
-	{
-		n := min(maxStones, m, 2*Size-1-maxStones1-y-x, x+y-maxStones1+1)
+// updateScores adds (sign = 1) or subtracts (sign = -1) the score changes
+// caused by a stone at x, y for every row of places that contains it. The
+// place itself has to be empty.
+func (b *Board) updateScores(turn Turn, x, y int, sign Score) {
+	for _, d := range rowDirections {
+		xStart, yStart, n := b.rows(x, y, d[0], d[1])
 		if n > 0 {
-			mn := min(Size-1-x, y, maxStones1)
-			xStart := x + mn
-			yStart := y - mn
-			if b.updateRow(turn, xStart, yStart, -1, 1, n) {
-				return true
-			}
+			b.updateRow(turn, xStart, yStart, d[0], d[1], n, sign)
 		}
 	}
-
-	if turn == First {
-		b.stones[y][x] = Black
-	} else {
-		b.stones[y][x] = White
-	}
-	b.validate()
-	return false
 }
 
-func (b *Board) updateRow(turn Turn, x, y, dx, dy, n int) bool {
+func (b *Board) updateRow(turn Turn, x, y, dx, dy, n int, sign Score) {
+	row, row1 := b.scoring.Row, b.scoring.Row-1
 	stones := Stone(0)
-	for i := 0; i < maxStones1; i++ {
+	for i := 0; i < row1; i++ {
 		stones += b.stones[y+i*dy][x+i*dx]
 	}
 	for range n {
-		stones += b.stones[y+maxStones1*dy][x+maxStones1*dx]
+		stones += b.stones[y+row1*dy][x+row1*dx]
 
-		if turn == First && stones == maxStones1 || turn == Second && stones == maxStones1*White {
-			return true
-		}
-		score := scoreStones(turn, stones)
+		score := sign * b.scoring.scoreStones(turn, stones)
 		if score != 0 {
-			for j := 0; j < maxStones; j++ {
+			for j := 0; j < row; j++ {
 				b.scores[y+j*dy][x+j*dx] += score
 			}
 		}
@@ -153,7 +276,6 @@ func (b *Board) updateRow(turn Turn, x, y, dx, dy, n int) bool {
 		x += dx
 		y += dy
 	}
-	return false
 }
 
 func (b *Board) Copy() *Board {
@@ -161,22 +283,22 @@ func (b *Board) Copy() *Board {
 	return &board
 }
 
-func (b *Board) Rollout(turn Turn, stonesPerMove int) Value {
+// Rollout plays the game out with stonesPerMove stones per move and returns
+// 1 when turn wins, -1 when it loses and 0 for a draw.
+func (b *Board) Rollout(turn Turn, stonesPerMove int, rollout Rollout) Value {
 	roTurn := turn
-	n := 1
+	n := 0
 	for {
 		for range stonesPerMove {
-			if n > 100 {
+			if rollout.Depth > 0 && n >= rollout.Depth {
 				return 0
 			}
 			n++
-			x, y, score := b.BestPlace(roTurn)
+			x, y, score := b.rolloutPlace(roTurn, rollout)
 			if score == 0 {
 				return 0
 			}
-			winner := b.PlaceStone(roTurn, x, y)
-			fmt.Printf("place %d: %v %c%d%v\n", n, roTurn, x+'a', y+1, b)
-			if winner {
+			if b.PlaceStone(roTurn, x, y) {
 				if turn == roTurn {
 					return 1
 				} else {
@@ -192,10 +314,30 @@ func (b *Board) Rollout(turn Turn, stonesPerMove int) Value {
 	}
 }
 
+// rolloutPlace picks the next place of a rollout. Under Renju scoring the
+// forbidden places of Black are hidden from the policy by clearing their
+// scores until it picks another one.
+func (b *Board) rolloutPlace(turn Turn, rollout Rollout) (int, int, Score) {
+	x, y, score := rollout.Policy.Place(b, turn, rollout.Rand)
+	if !b.scoring.Renju || turn != First {
+		return x, y, score
+	}
+	hidden := []Place{}
+	for score != 0 && b.Forbidden(x, y) {
+		hidden = append(hidden, Place{int8(x), int8(y), b.scores[y][x]})
+		b.scores[y][x] = 0
+		x, y, score = rollout.Policy.Place(b, turn, rollout.Rand)
+	}
+	for _, place := range hidden {
+		b.scores[place.Y][place.X] = place.Score
+	}
+	return x, y, score
+}
+
 func (b *Board) BestPlace(turn Turn) (int, int, Score) {
 	xx, yy, bestScore := 0, 0, Score(0)
-	for y := range Size {
-		for x := range Size {
+	for y := range b.height {
+		for x := range b.width {
 			if b.stones[y][x] != None {
 				continue
 			}
@@ -224,14 +366,14 @@ func (b *Board) GoString() string {
 func (b *Board) BoardString(buf *bytes.Buffer) {
 	buf.WriteString("\n  ")
 
-	for i := range Size {
+	for i := range b.width {
 		fmt.Fprintf(buf, " %c", i+'a')
 	}
 	buf.WriteByte('\n')
 
-	for y := range Size {
+	for y := range b.height {
 		fmt.Fprintf(buf, "%2d", y+1)
-		for x := range Size {
+		for x := range b.width {
 			switch b.stones[y][x] {
 			case Black:
 				if x == 0 {
@@ -251,16 +393,16 @@ func (b *Board) BoardString(buf *bytes.Buffer) {
 					switch x {
 					case 0:
 						buf.WriteString(" ┌")
-					case Size - 1:
+					case b.width - 1:
 						buf.WriteString("─┐")
 					default:
 						buf.WriteString("─┬")
 					}
-				case Size - 1:
+				case b.height - 1:
 					switch x {
 					case 0:
 						buf.WriteString(" └")
-					case Size - 1:
+					case b.width - 1:
 						buf.WriteString("─┘")
 					default:
 						buf.WriteString("─┴")
@@ -269,7 +411,7 @@ func (b *Board) BoardString(buf *bytes.Buffer) {
 					switch x {
 					case 0:
 						buf.WriteString(" ├")
-					case Size - 1:
+					case b.width - 1:
 						buf.WriteString("─┤")
 					default:
 						buf.WriteString("─┼")
@@ -282,7 +424,7 @@ func (b *Board) BoardString(buf *bytes.Buffer) {
 
 	buf.WriteString("  ")
 
-	for i := range Size {
+	for i := range b.width {
 		fmt.Fprintf(buf, " %c", i+'a')
 	}
 	buf.WriteByte('\n')
@@ -291,19 +433,19 @@ func (b *Board) BoardString(buf *bytes.Buffer) {
 func (b *Board) ScoresString(buf *bytes.Buffer) {
 	buf.WriteString("\n      │")
 
-	for i := range Size {
+	for i := range b.width {
 		fmt.Fprintf(buf, " %c %2d │", i+'a', i)
 	}
 	buf.WriteString("\n")
 
-	for range Size {
+	for range b.width {
 		fmt.Fprintf(buf, "──────┼")
 	}
 	fmt.Fprintln(buf, "──────┤")
-	for y := 0; y < Size; y++ {
-		fmt.Fprintf(buf, "%2d %2d │", Size-y, y)
+	for y := 0; y < b.height; y++ {
+		fmt.Fprintf(buf, "%2d %2d │", b.height-y, y)
 
-		for x := 0; x < Size; x++ {
+		for x := 0; x < b.width; x++ {
 			switch b.stones[y][x] {
 			case None:
 				fmt.Fprintf(buf, "%5d │", b.scores[y][x])
@@ -316,13 +458,13 @@ func (b *Board) ScoresString(buf *bytes.Buffer) {
 
 		buf.WriteByte('\n')
 	}
-	for range Size {
+	for range b.width {
 		fmt.Fprintf(buf, "──────┼")
 	}
 	fmt.Fprintln(buf, "──────┤")
 	buf.WriteString("      │")
 
-	for i := range Size {
+	for i := range b.width {
 		fmt.Fprintf(buf, " %c %2d │", i+'a', i)
 	}
 	buf.WriteString("\n")
@@ -347,7 +489,19 @@ func ParsePlace(place string) (int8, int8, error) {
 		y = 10*y + int8(place[2]-'0')
 	}
 	y -= 1
-	if x >= Size || y >= Size {
+	if x >= MaxSize || y < 0 || y >= MaxSize {
+		return 0, 0, errors.New("failed to parse place")
+	}
+	return x, y, nil
+}
+
+// ParsePlace parses a place and checks that it is on the board.
+func (b *Board) ParsePlace(place string) (int8, int8, error) {
+	x, y, err := ParsePlace(place)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !b.Contains(int(x), int(y)) {
 		return 0, 0, errors.New("failed to parse place")
 	}
 	return x, y, nil
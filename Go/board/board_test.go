@@ -3,6 +3,8 @@ package board
 import (
 	"bytes"
 	"fmt"
+	"math/rand/v2"
+	"slices"
 	"testing"
 
 	. "monte/common"
@@ -14,8 +16,10 @@ type testPlace struct {
 	turn Turn
 }
 
+var testSizes = [][2]int{{19, 19}, {15, 15}, {11, 11}, {9, 9}, {15, 10}, {7, 19}}
+
 func TestPlaceStone(t *testing.T) {
-	b := MakeBoard()
+	b := MakeBoard(19, 19, Connect6Scoring)
 	// fmt.Printf("%#v\n", &b)
 	b.PlaceStone(First, 9, 9)
 	b.PlaceStone(Second, 8, 8)
@@ -26,7 +30,7 @@ func TestPlaceStone(t *testing.T) {
 }
 
 func TestPlaceStones(t *testing.T) {
-	b := MakeBoard()
+	b := MakeBoard(19, 19, Connect6Scoring)
 	b.PlaceStone(First, 9, 9)
 	b.PlaceStone(Second, 8, 8)
 	b.PlaceStone(Second, 8, 10)
@@ -35,13 +39,16 @@ func TestPlaceStones(t *testing.T) {
 outer:
 	for {
 		for range 2 {
-			x, y, _ := b.BestPlace(turn)
+			x, y, score := b.BestPlace(turn)
+			if score == 0 {
+				break outer
+			}
 			fmt.Printf("place %v [%d:%d]\n", turn, x, y)
 			if b.PlaceStone(turn, x, y) {
 				break outer
 			}
 			board := b.Copy()
-			ro := int(board.Rollout(turn, 2))
+			ro := int(board.Rollout(turn, 2, DefaultRollout))
 			m[ro]++
 		}
 		if turn == First {
@@ -56,7 +63,7 @@ outer:
 
 func TestTopPlaces(t *testing.T) {
 	places := make([]Place, 0, 30)
-	board := MakeBoard()
+	board := MakeBoard(19, 19, Connect6Scoring)
 	board.PlaceStone(First, 9, 9)
 	board.PlaceStone(Second, 8, 8)
 	board.PlaceStone(Second, 8, 10)
@@ -73,15 +80,15 @@ func TestTopPlaces(t *testing.T) {
 }
 
 func TestRollout(t *testing.T) {
-	board := MakeBoard()
+	board := MakeBoard(19, 19, Connect6Scoring)
 	board.PlaceStone(First, 9, 9)
 	board.PlaceStone(Second, 8, 8)
 	board.PlaceStone(Second, 8, 10)
 	b := board.Copy()
-	fmt.Println(b.Rollout(First, 2))
+	fmt.Println(b.Rollout(First, 2, DefaultRollout))
 	fmt.Printf("%#v\n", &board)
 
-	board2 := MakeBoard()
+	board2 := MakeBoard(19, 19, Connect6Scoring)
 	board2.PlaceStone(First, 9, 9)
 	board2.PlaceStone(Second, 8, 8)
 	board2.PlaceStone(Second, 8, 10)
@@ -90,8 +97,372 @@ func TestRollout(t *testing.T) {
 	}
 }
 
+func TestRolloutPolicies(t *testing.T) {
+	board := MakeBoard(19, 19, Connect6Scoring)
+	board.PlaceStone(First, 9, 9)
+	board.PlaceStone(Second, 8, 8)
+	board.PlaceStone(Second, 8, 10)
+	policies := []Policy{Greedy{}, TopK{K: 5}, Softmax{Temperature: 20}, EpsilonGreedy{Epsilon: 0.2}}
+	for _, policy := range policies {
+		results := map[Value]int{}
+		for seed := range uint64(20) {
+			rollout := Rollout{Policy: policy, Rand: rand.New(rand.NewPCG(seed, 1))}
+			copy := board.Copy()
+			results[copy.Rollout(First, 2, rollout)]++
+
+			again := board
+			rollout.Rand = rand.New(rand.NewPCG(seed, 1))
+			again.Rollout(First, 2, rollout)
+			if again != *copy {
+				fmt.Printf("%T seed %d is not reproducible\n", policy, seed)
+				t.Fail()
+			}
+		}
+		_, greedy := policy.(Greedy)
+		fmt.Printf("%T %v\n", policy, results)
+		if greedy != (len(results) == 1) {
+			fmt.Printf("%T %v\n", policy, results)
+			t.Fail()
+		}
+	}
+
+	for _, policy := range policies {
+		rollout := Rollout{Policy: policy, Rand: rand.New(rand.NewPCG(1, 2)), Depth: 3}
+		copy := board.Copy()
+		if copy.Rollout(First, 2, rollout) != 0 || copy.placed != board.placed+3 {
+			fmt.Printf("%T depth %d\n", policy, copy.placed-board.placed)
+			t.Fail()
+		}
+	}
+
+	_, _, best := board.BestPlace(First)
+	for _, policy := range []Policy{TopK{K: 0}, TopK{K: -1}, Softmax{Temperature: 0}, Softmax{Temperature: -1}} {
+		if _, _, score := policy.Place(&board, First, rand.New(rand.NewPCG(1, 2))); score != best {
+			fmt.Printf("%#v %d\n", policy, score)
+			t.Fail()
+		}
+	}
+}
+
+func TestMakeBoard(t *testing.T) {
+	for _, size := range testSizes {
+		board := MakeBoard(size[0], size[1], Connect6Scoring)
+		for y := range MaxSize {
+			for x := range MaxSize {
+				rows := 0
+				for _, d := range rowDirections {
+					for i := range board.scoring.Row {
+						xStart, yStart := x-i*d[0], y-i*d[1]
+						xEnd, yEnd := xStart+(board.scoring.Row-1)*d[0], yStart+(board.scoring.Row-1)*d[1]
+						if board.Contains(xStart, yStart) && board.Contains(xEnd, yEnd) {
+							rows++
+						}
+					}
+				}
+				if board.scores[y][x] != Score(rows)*oneStone {
+					fmt.Printf("%v [%d:%d] expected %d got %d\n", size, x, y, rows, board.scores[y][x])
+					t.Fail()
+				}
+			}
+		}
+	}
+}
+
+func TestDefaultScoring(t *testing.T) {
+	scoring := DefaultScoring(MaxDefaultRow)
+	if !slices.Equal(scoring.Values, Connect6Scoring.Values) {
+		fmt.Println(scoring.Values)
+		t.Fail()
+	}
+	board := MakeBoard(19, 19, scoring)
+	for _, x := range []int{4, 5, 6, 7, 8, 10, 11, 12, 13, 14} {
+		board.PlaceStone(First, x, 9)
+		board.PlaceStone(First, 9, x)
+		board.PlaceStone(First, x, x)
+		board.PlaceStone(First, x, 18-x)
+	}
+	if score := board.Score(9, 9); score <= 0 || int(score) > 4*MaxDefaultRow*int(scoring.Values[MaxDefaultRow-1]) {
+		fmt.Println(score)
+		t.Fail()
+	}
+
+	if !panics(func() { DefaultScoring(MaxDefaultRow + 1) }) || !panics(func() { NewScoring(7, 1, 7, 42, 210, 840, 2520, 5040) }) {
+		t.Fail()
+	}
+}
+
+// panics reports whether f panics.
+func panics(f func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+	f()
+	return false
+}
+
+func TestParsePlace(t *testing.T) {
+	board := MakeBoard(15, 10, Connect6Scoring)
+	if x, y, err := board.ParsePlace("o10"); x != 14 || y != 9 || err != nil {
+		t.Fail()
+	}
+	for _, place := range []string{"p1", "a11", "a0", "s20", "1a", "a"} {
+		if _, _, err := board.ParsePlace(place); err == nil {
+			fmt.Println(place)
+			t.Fail()
+		}
+	}
+	if x, y, err := ParsePlace("s19"); x != 18 || y != 18 || err != nil {
+		t.Fail()
+	}
+}
+
+func TestBoardString(t *testing.T) {
+	board := MakeBoard(7, 3, Connect6Scoring)
+	board.PlaceStone(First, 0, 0)
+	board.PlaceStone(Second, 6, 2)
+	expected := `
+   a b c d e f g
+ 1 X─┬─┬─┬─┬─┬─┐  1
+ 2 ├─┼─┼─┼─┼─┼─┤  2
+ 3 └─┴─┴─┴─┴─┴─O  3
+   a b c d e f g
+`
+	if board.String() != expected {
+		fmt.Println(board.String())
+		t.Fail()
+	}
+}
+
+func TestHashTransposition(t *testing.T) {
+	board := MakeBoard(19, 19, Connect6Scoring)
+	board.PlaceStone(First, 9, 9)
+	board.PlaceStone(Second, 8, 8)
+	board.PlaceStone(Second, 8, 10)
+
+	board2 := MakeBoard(19, 19, Connect6Scoring)
+	board2.PlaceStone(First, 9, 9)
+	board2.PlaceStone(Second, 8, 10)
+	board2.PlaceStone(Second, 8, 8)
+	if board.Hash() != board2.Hash() {
+		t.Fail()
+	}
+
+	board3 := MakeBoard(19, 19, Connect6Scoring)
+	board3.PlaceStone(First, 9, 9)
+	board3.PlaceStone(First, 8, 10)
+	board3.PlaceStone(Second, 8, 8)
+	empty := MakeBoard(19, 19, Connect6Scoring)
+	if board.Hash() == board3.Hash() || board.Hash() == empty.Hash() {
+		t.Fail()
+	}
+}
+
+func TestHashRandomMoves(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for game := range 60 {
+		size := testSizes[game%len(testSizes)]
+		board := MakeBoard(size[0], size[1], Connect6Scoring)
+		turn := First
+	moves:
+		for range rng.IntN(size[0] * size[1] / 2) {
+			for range 2 {
+				x, y := rng.IntN(size[0]), rng.IntN(size[1])
+				if board.stones[y][x] != None {
+					continue
+				}
+				if board.PlaceStone(turn, x, y) {
+					break moves
+				}
+				if board.Hash() != board.computeHash() {
+					fmt.Printf("game %d: hash %x expected %x\n", game, board.Hash(), board.computeHash())
+					t.FailNow()
+				}
+			}
+			if turn == First {
+				turn = Second
+			} else {
+				turn = First
+			}
+		}
+		if board.Hash() != board.computeHash() {
+			t.Fail()
+		}
+	}
+}
+
+func TestRemoveStone(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for game := range 60 {
+		size := testSizes[game%len(testSizes)]
+		board := MakeBoard(size[0], size[1], Connect6Scoring)
+		boards := []Board{board}
+		places := []testPlace{}
+		turn := First
+	moves:
+		for range rng.IntN(size[0] * size[1] / 2) {
+			for range 2 {
+				x, y := rng.IntN(size[0]), rng.IntN(size[1])
+				if board.stones[y][x] != None {
+					continue
+				}
+				winner := board.PlaceStone(turn, x, y)
+				boards = append(boards, board)
+				places = append(places, testPlace{x, y, turn})
+				if winner {
+					if !board.IsOver() || board.Winner() != board.stones[y][x] {
+						t.Fatal("winning stone did not end the game")
+					}
+					break moves
+				}
+			}
+			if turn == First {
+				turn = Second
+			} else {
+				turn = First
+			}
+		}
+		for i := len(places) - 1; i >= 0; i-- {
+			board.RemoveStone(places[i].x, places[i].y)
+			if board != boards[i] {
+				fmt.Printf("%#v\n", &board)
+				t.Fatalf("remove %v", places[i])
+			}
+		}
+		if !panics(func() { board.RemoveStone(0, 0) }) {
+			t.Fatal("removed a stone from an empty place")
+		}
+	}
+}
+
+func TestGameOver(t *testing.T) {
+	board := MakeBoard(19, 19, Connect6Scoring)
+	for i := range 5 {
+		board.PlaceStone(First, 3+i, 10-i)
+		board.PlaceStone(Second, 0, i)
+	}
+	if board.IsOver() || board.Winner() != None || board.WinningLine() != nil {
+		t.Fail()
+	}
+	before := board
+	if !board.PlaceStone(First, 2, 11) {
+		t.Fatal("expected a win")
+	}
+	line := board.WinningLine()
+	if !board.IsOver() || board.IsDraw() || board.Winner() != Black || len(line) != 6 {
+		fmt.Println(board.IsOver(), board.IsDraw(), board.Winner(), line)
+		t.Fail()
+	}
+	for i, place := range line {
+		if place.X != int8(7-i) || place.Y != int8(6+i) {
+			fmt.Println(line)
+			t.Fail()
+		}
+	}
+	board.RemoveStone(2, 11)
+	if board != before {
+		t.Fail()
+	}
+
+	small := MakeBoard(3, 3, Connect6Scoring)
+	turn := First
+	for i := range 9 {
+		if small.IsOver() || small.PlaceStone(turn, i%3, i/3) {
+			t.Fatal("unexpected end of game")
+		}
+		turn = 1 - turn
+	}
+	if !small.IsOver() || !small.IsDraw() || small.Winner() != None {
+		t.Fail()
+	}
+}
+
+func TestExactRow(t *testing.T) {
+	for _, scoring := range []*Scoring{GomokuScoring, StandardGomokuScoring} {
+		board := MakeBoard(15, 15, scoring)
+		for _, x := range []int{3, 4, 6, 7, 8} {
+			if board.PlaceStone(First, x, 7) {
+				t.Fatal("unexpected win")
+			}
+		}
+		if board.PlaceStone(First, 5, 7) == scoring.Exact {
+			fmt.Println("exact", scoring.Exact, "overline")
+			t.Fail()
+		}
+		if !scoring.Exact {
+			continue
+		}
+		for y := range 4 {
+			board.PlaceStone(Second, 0, y)
+		}
+		if !board.PlaceStone(Second, 0, 4) {
+			fmt.Println("exact", scoring.Exact, "five")
+			t.Fail()
+		}
+	}
+}
+
+func TestRenjuRollout(t *testing.T) {
+	for _, turn := range []Turn{First, Second} {
+		board := MakeBoard(15, 15, RenjuScoring)
+		for _, x := range []int{1, 2, 3, 5, 6, 7} {
+			board.PlaceStone(turn, x, 7)
+		}
+		copy, expected := board, Value(0)
+		if turn == Second {
+			expected = 1
+		}
+		if copy.Rollout(turn, 1, Rollout{Policy: Greedy{}, Depth: 1}) != expected || (copy.Stone(4, 7) == None) != (turn == First) {
+			fmt.Println(turn, &copy)
+			t.Fail()
+		}
+		if board.PlaceStone(turn, 4, 7) != (turn == Second) {
+			fmt.Println(turn, "overline")
+			t.Fail()
+		}
+	}
+}
+
+func TestForbidden(t *testing.T) {
+	tests := []struct {
+		name         string
+		black, white [][2]int
+		forbidden    bool
+	}{
+		{"double three", [][2]int{{5, 7}, {6, 7}, {7, 5}, {7, 6}}, nil, true},
+		{"double four", [][2]int{{4, 7}, {5, 7}, {6, 7}, {7, 4}, {7, 5}, {7, 6}}, [][2]int{{3, 7}, {7, 3}}, true},
+		{"double four in a line", [][2]int{{3, 7}, {5, 7}, {6, 7}, {9, 7}}, nil, true},
+		{"five", [][2]int{{3, 7}, {4, 7}, {5, 7}, {6, 7}, {7, 4}, {7, 5}, {7, 6}}, nil, false},
+		{"four three", [][2]int{{4, 7}, {5, 7}, {6, 7}, {7, 5}, {7, 6}}, [][2]int{{3, 7}}, false},
+		{"closed three", [][2]int{{5, 7}, {6, 7}, {7, 5}, {7, 6}}, [][2]int{{7, 4}, {7, 8}}, false},
+		{"three with an overline four", [][2]int{{5, 7}, {6, 7}, {7, 5}, {7, 6}, {7, 10}}, [][2]int{{7, 3}}, false},
+		{"three", [][2]int{{5, 7}, {6, 7}, {7, 5}, {7, 6}, {7, 11}}, [][2]int{{7, 3}}, true},
+	}
+	for _, test := range tests {
+		board := MakeBoard(15, 15, GomokuScoring)
+		for _, place := range test.black {
+			board.PlaceStone(First, place[0], place[1])
+		}
+		for _, place := range test.white {
+			board.PlaceStone(Second, place[0], place[1])
+		}
+		if board.Forbidden(7, 7) != test.forbidden {
+			fmt.Println(test.name, &board)
+			t.Fail()
+		}
+	}
+
+	board := MakeBoard(15, 15, GomokuScoring)
+	for _, x := range []int{1, 2, 3, 5, 6} {
+		board.PlaceStone(First, x, 7)
+	}
+	if !board.Forbidden(4, 7) {
+		fmt.Println("overline", &board)
+		t.Fail()
+	}
+}
+
 func BenchmarkCopyBoard(b *testing.B) {
-	board := MakeBoard()
+	board := MakeBoard(19, 19, Connect6Scoring)
 	b.ResetTimer()
 	for range b.N {
 		board2 := board
@@ -100,17 +471,83 @@ func BenchmarkCopyBoard(b *testing.B) {
 }
 
 func BenchmarkRollout(b *testing.B) {
-	board := MakeBoard()
+	board := MakeBoard(19, 19, Connect6Scoring)
 	b.ResetTimer()
 	for range b.N {
 		copy := board.Copy()
-		copy.Rollout(First, 2)
+		copy.Rollout(First, 2, DefaultRollout)
 	}
 }
+
+func BenchmarkPlaceRemoveStone(b *testing.B) {
+	board := MakeBoard(19, 19, Connect6Scoring)
+	b.ResetTimer()
+	for range b.N {
+		board.PlaceStone(First, 9, 9)
+		board.RemoveStone(9, 9)
+	}
+}
+
 func BenchmarkBestPlace(b *testing.B) {
-	board := MakeBoard()
+	board := MakeBoard(19, 19, Connect6Scoring)
 	b.ResetTimer()
 	for range b.N {
 		board.BestPlace(First)
 	}
 }
+
+func TestPosition(t *testing.T) {
+	board := MakeBoard(3, 2, Connect6Scoring)
+	if board.Position() != "3/3" {
+		fmt.Println(board.Position())
+		t.Fail()
+	}
+	board.PlaceStone(First, 0, 0)
+	board.PlaceStone(Second, 2, 1)
+	if board.Position() != "x2/2o" {
+		fmt.Println(board.Position())
+		t.Fail()
+	}
+
+	rng := rand.New(rand.NewPCG(3, 4))
+	for game := range 30 {
+		size := testSizes[game%len(testSizes)]
+		board := MakeBoard(size[0], size[1], Connect6Scoring)
+		turn := First
+		for range rng.IntN(size[0] * size[1] / 2) {
+			x, y := rng.IntN(size[0]), rng.IntN(size[1])
+			if board.stones[y][x] != None {
+				continue
+			}
+			if board.PlaceStone(turn, x, y) {
+				break
+			}
+			turn = 1 - turn
+		}
+		parsed, err := ParsePosition(board.Position(), Connect6Scoring)
+		if err != nil || parsed != board {
+			fmt.Println(board.Position(), err)
+			t.Fail()
+		}
+	}
+
+	won := MakeBoard(19, 19, Connect6Scoring)
+	for i := range 6 {
+		won.PlaceStone(First, 3+i, 10-i)
+		if i < 5 {
+			won.PlaceStone(Second, 0, i)
+		}
+	}
+	parsed, err := ParsePosition(won.Position(), Connect6Scoring)
+	if err != nil || parsed != won || parsed.Winner() != Black {
+		fmt.Println(won.Position(), err)
+		t.Fail()
+	}
+
+	for _, position := range []string{"", "3/2", "x2/3o", "3/a2", "20", "xxxxxx/oooooo", "99999999999999999999x"} {
+		if _, err := ParsePosition(position, Connect6Scoring); err == nil {
+			fmt.Println("expected an error for", position)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package board
+
+var (
+	GomokuScoring         = NewScoring(5, 1, 6, 36, 216, 1296)
+	StandardGomokuScoring = GomokuScoring.Exactly()
+	RenjuScoring          = GomokuScoring.ForRenju()
+)
@@ -0,0 +1,111 @@
+package board
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	. "monte/common"
+)
+
+// Position returns the stones in a compact notation: the rows from row 1
+// separated by "/", with "x" for black, "o" for white and numbers for runs of
+// empty places. The empty 3x2 board is "3/3".
+func (b *Board) Position() string {
+	buf := strings.Builder{}
+	for y := range b.height {
+		if y > 0 {
+			buf.WriteByte('/')
+		}
+		empty := 0
+		for x := range b.width {
+			if b.stones[y][x] == None {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				buf.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			if b.stones[y][x] == Black {
+				buf.WriteByte('x')
+			} else {
+				buf.WriteByte('o')
+			}
+		}
+		if empty > 0 {
+			buf.WriteString(strconv.Itoa(empty))
+		}
+	}
+	return buf.String()
+}
+
+var errPosition = errors.New("failed to parse position")
+
+// ParsePosition returns the board of a position written by Position. The
+// stones that win are placed last, so a position can hold one winning row.
+func ParsePosition(position string, scoring *Scoring) (Board, error) {
+	rows := strings.Split(position, "/")
+	width := -1
+	type stone struct {
+		x, y  int
+		stone Stone
+	}
+	stones := []stone{}
+	for y, row := range rows {
+		x := 0
+		for i := 0; i < len(row); i++ {
+			switch c := row[i]; {
+			case c == 'x':
+				stones = append(stones, stone{x, y, Black})
+				x++
+			case c == 'o':
+				stones = append(stones, stone{x, y, White})
+				x++
+			case c >= '1' && c <= '9':
+				n := int(c - '0')
+				for i+1 < len(row) && row[i+1] >= '0' && row[i+1] <= '9' {
+					i++
+					n = 10*n + int(row[i]-'0')
+					if n > MaxSize {
+						return Board{}, errPosition
+					}
+				}
+				x += n
+			default:
+				return Board{}, errPosition
+			}
+		}
+		if width != -1 && x != width {
+			return Board{}, errPosition
+		}
+		width = x
+	}
+	if width < 1 || width > MaxSize || len(rows) > MaxSize {
+		return Board{}, errPosition
+	}
+
+	b := MakeBoard(width, len(rows), scoring)
+	winning := []stone{}
+	place := func(s stone) {
+		turn := First
+		if s.stone == White {
+			turn = Second
+		}
+		b.PlaceStone(turn, s.x, s.y)
+	}
+	for _, s := range stones {
+		if _, wins := b.winningLine(s.stone, s.x, s.y); wins {
+			winning = append(winning, s)
+			continue
+		}
+		place(s)
+	}
+	for _, s := range winning {
+		if b.IsOver() {
+			return Board{}, errors.New("position with more than one winning row")
+		}
+		place(s)
+	}
+	return b, nil
+}
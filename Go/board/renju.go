@@ -0,0 +1,115 @@
+package board
+
+// In Renju Black may not play a double-three, a double-four or an overline,
+// unless the stone also makes exactly five in a row. White has no
+// restrictions. Forbidden analyses the lines through a place one direction at
+// a time; a three only counts when its straight four would not be an overline.
+
+// renjuReach is how far from the place the lines are looked at.
+const renjuReach = 5
+
+type renjuLine [2*renjuReach + 1]Stone
+
+// wall marks a place beyond the board edge.
+const wall Stone = 0xff
+
+// Forbidden reports whether a black stone on the empty place x, y is a Renju
+// forbidden move. It does not depend on Row: Renju is five in a row.
+func (b *Board) Forbidden(x, y int) bool {
+	fours, threes, overline := 0, 0, false
+	for _, d := range rowDirections {
+		line := b.renjuLine(x, y, d[0], d[1])
+		line[renjuReach] = Black
+		run := line.run(renjuReach)
+		if run == 5 {
+			return false
+		}
+		if run > 5 {
+			overline = true
+			continue
+		}
+		if n := line.fours(renjuReach); n > 0 {
+			fours += n
+		} else if line.three(renjuReach) {
+			threes++
+		}
+	}
+	return overline || fours >= 2 || threes >= 2
+}
+
+func (b *Board) renjuLine(x, y, dx, dy int) renjuLine {
+	line := renjuLine{}
+	for i := range line {
+		xx, yy := x+(i-renjuReach)*dx, y+(i-renjuReach)*dy
+		if b.Contains(xx, yy) {
+			line[i] = b.stones[yy][xx]
+		} else {
+			line[i] = wall
+		}
+	}
+	return line
+}
+
+// run returns the number of black stones in a row through i.
+func (line *renjuLine) run(i int) int {
+	n := 1
+	for j := i - 1; j >= 0 && line[j] == Black; j-- {
+		n++
+	}
+	for j := i + 1; j < len(line) && line[j] == Black; j++ {
+		n++
+	}
+	return n
+}
+
+// fivePoints returns the empty places that make exactly five in a row
+// together with the black stone at i.
+func (line *renjuLine) fivePoints(i int, points *[2 * renjuReach]int) int {
+	n := 0
+	for q := max(0, i-4); q <= min(len(line)-1, i+4); q++ {
+		if line[q] != None {
+			continue
+		}
+		line[q] = Black
+		if line.run(q) == 5 && line.run(i) == 5 {
+			points[n] = q
+			n++
+		}
+		line[q] = None
+	}
+	return n
+}
+
+// fours returns the number of fours through i. A straight four, with two ways
+// to make five, counts as one.
+func (line *renjuLine) fours(i int) int {
+	points := [2 * renjuReach]int{}
+	n := line.fivePoints(i, &points)
+	if n == 2 && points[1]-points[0] == 5 {
+		return 1
+	}
+	return n
+}
+
+// straightFour reports whether the stone at i is part of a four with two ways
+// to make five.
+func (line *renjuLine) straightFour(i int) bool {
+	points := [2 * renjuReach]int{}
+	return line.fivePoints(i, &points) == 2 && points[1]-points[0] == 5
+}
+
+// three reports whether one more black stone makes a straight four through i.
+func (line *renjuLine) three(i int) bool {
+	for q := max(0, i-4); q <= min(len(line)-1, i+4); q++ {
+		if line[q] != None {
+			continue
+		}
+		line[q] = Black
+		isThree := line.run(q) < 6 && line.straightFour(i)
+		line[q] = None
+		if isThree {
+			return true
+		}
+	}
+	return false
+}
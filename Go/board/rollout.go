@@ -0,0 +1,124 @@
+package board
+
+import (
+	"math"
+	"math/rand/v2"
+
+	. "monte/common"
+	"monte/heap"
+)
+
+// Rollout configures Board.Rollout. Policy picks the places, Rand drives the
+// random policies and has to be set for them, and Depth limits the number of
+// stones placed, with 0 for no limit. A rollout reaching the limit counts as a
+// draw.
+type Rollout struct {
+	Policy Policy
+	Rand   *rand.Rand
+	Depth  int
+}
+
+// DefaultRollout always plays the best place.
+var DefaultRollout = Rollout{Policy: Greedy{}, Depth: 100}
+
+// Policy picks the next place of a rollout among the empty places with a
+// positive score. A zero score means that no such place is left.
+type Policy interface {
+	Place(b *Board, turn Turn, rng *rand.Rand) (int, int, Score)
+}
+
+// Greedy plays the best place.
+type Greedy struct{}
+
+func (Greedy) Place(b *Board, turn Turn, rng *rand.Rand) (int, int, Score) {
+	return b.BestPlace(turn)
+}
+
+// TopK plays one of the K best places with equal probability. K below 1
+// plays the best place.
+type TopK struct {
+	K int
+}
+
+func (p TopK) Place(b *Board, turn Turn, rng *rand.Rand) (int, int, Score) {
+	places := make([]Place, 0, max(1, p.K))
+	b.forPlaces(func(x, y int, score Score) bool {
+		heap.Add(&places, Place{int8(x), int8(y), score})
+		return true
+	})
+	if len(places) == 0 {
+		return 0, 0, 0
+	}
+	place := places[rng.IntN(len(places))]
+	return int(place.X), int(place.Y), place.Score
+}
+
+// Softmax plays a place with probability proportional to exp(score /
+// Temperature). A Temperature of 0 or below plays the best place, the limit
+// of ever lower temperatures.
+type Softmax struct {
+	Temperature float64
+}
+
+func (p Softmax) Place(b *Board, turn Turn, rng *rand.Rand) (int, int, Score) {
+	x, y, bestScore := b.BestPlace(turn)
+	if bestScore == 0 || p.Temperature <= 0 {
+		return x, y, bestScore
+	}
+	sum := 0.0
+	b.forPlaces(func(x, y int, score Score) bool {
+		sum += math.Exp(float64(score-bestScore) / p.Temperature)
+		return true
+	})
+	target := rng.Float64() * sum
+	xx, yy, placeScore := 0, 0, Score(0)
+	b.forPlaces(func(x, y int, score Score) bool {
+		xx, yy, placeScore = x, y, score
+		target -= math.Exp(float64(score-bestScore) / p.Temperature)
+		return target > 0
+	})
+	return xx, yy, placeScore
+}
+
+// EpsilonGreedy plays a place chosen uniformly with probability Epsilon and
+// the best place otherwise.
+type EpsilonGreedy struct {
+	Epsilon float64
+}
+
+func (p EpsilonGreedy) Place(b *Board, turn Turn, rng *rand.Rand) (int, int, Score) {
+	if rng.Float64() >= p.Epsilon {
+		return b.BestPlace(turn)
+	}
+	n := 0
+	b.forPlaces(func(x, y int, score Score) bool {
+		n++
+		return true
+	})
+	if n == 0 {
+		return 0, 0, 0
+	}
+	i := rng.IntN(n)
+	xx, yy, placeScore := 0, 0, Score(0)
+	b.forPlaces(func(x, y int, score Score) bool {
+		xx, yy, placeScore = x, y, score
+		i--
+		return i >= 0
+	})
+	return xx, yy, placeScore
+}
+
+// forPlaces calls f for the empty places with a positive score until f
+// returns false.
+func (b *Board) forPlaces(f func(x, y int, score Score) bool) {
+	for y := range b.height {
+		for x := range b.width {
+			if b.stones[y][x] != None || b.scores[y][x] <= 0 {
+				continue
+			}
+			if !f(x, y, b.scores[y][x]) {
+				return
+			}
+		}
+	}
+}
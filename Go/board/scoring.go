@@ -0,0 +1,118 @@
+package board
+
+import (
+	"fmt"
+	"math"
+
+	. "monte/common"
+)
+
+// Scoring describes how places are scored in a variant where Row stones in a
+// row win, or exactly Row stones when Exact is set. A row of Row places
+// holding i stones of a single color is worth Values[i]; rows holding stones
+// of both colors are worth nothing. The score of a place is the sum of the
+// rows that contain it. With Renju set only exactly Row black stones win, and
+// Board.Rollout does not play the Renju forbidden places for Black.
+type Scoring struct {
+	Row    int
+	Exact  bool
+	Renju  bool
+	Values []Score
+	deltas [2][256]Score
+}
+
+// NewScoring builds the scoring for rows of row places from the row values.
+// A place is in 4*row rows, so the values must not exceed MaxScore/(4*row)
+// for the scores of the places to fit in a Score.
+func NewScoring(row int, values ...Score) *Scoring {
+	if row < 2 || row > 15 || len(values) != row {
+		panic(fmt.Sprintf("NewScoring(%d, %v): invalid scoring", row, values))
+	}
+	for _, value := range values {
+		if 4*row*max(int(value), -int(value)) > MaxScore {
+			panic(fmt.Sprintf("NewScoring(%d, %v): scores out of range", row, values))
+		}
+	}
+	s := &Scoring{Row: row, Values: values}
+	for own := range row {
+		for other := range row - own {
+			stones := Stone(own)*Black + Stone(other)*White
+			s.deltas[First][stones] = s.delta(own, other)
+			stones = Stone(other)*Black + Stone(own)*White
+			s.deltas[Second][stones] = s.delta(own, other)
+		}
+	}
+	return s
+}
+
+// MaxScore is the largest score of a place.
+const MaxScore = math.MaxInt16
+
+// MaxDefaultRow is the longest row DefaultScoring supports.
+const MaxDefaultRow = 6
+
+// DefaultScoring scores a row of i stones of a single color as the number of
+// ordered ways to pick i of the row places. For rows of six that is the
+// Connect6 scoring. Longer rows than MaxDefaultRow give scores out of range.
+func DefaultScoring(row int) *Scoring {
+	if row < 2 || row > MaxDefaultRow {
+		panic(fmt.Sprintf("DefaultScoring(%d): unsupported row", row))
+	}
+	values := make([]Score, row)
+	value := 1
+	for i := range row {
+		values[i] = Score(value)
+		value *= row - i
+	}
+	return NewScoring(row, values...)
+}
+
+// Exactly returns a copy of the scoring where rows longer than Row do not win.
+func (s *Scoring) Exactly() *Scoring {
+	exact := *s
+	exact.Exact = true
+	return &exact
+}
+
+// ForRenju returns a copy of the scoring where rows of more than Row black
+// stones do not win.
+func (s *Scoring) ForRenju() *Scoring {
+	renju := *s
+	renju.Renju = true
+	return &renju
+}
+
+// wins reports whether n stones in a row win.
+func (s *Scoring) wins(stone Stone, n int) bool {
+	return n == s.Row || n > s.Row && !s.Exact && !(s.Renju && stone == Black)
+}
+
+// delta is the change of the row value when a stone is added to a row that
+// already has own stones of the same color and other stones of the other one.
+func (s *Scoring) delta(own, other int) Score {
+	switch {
+	case other == 0 && own+1 < s.Row:
+		return s.Values[own+1] - s.Values[own]
+	case other == 0:
+		return -s.Values[own]
+	case own == 0:
+		return -s.Values[other]
+	}
+	return 0
+}
+
+func (s *Scoring) scoreStones(turn Turn, stones Stone) Score {
+	return s.deltas[turn][stones]
+}
+
+// rowValue is the value of a row holding the stones.
+func (s *Scoring) rowValue(stones Stone) Score {
+	black, white := int(stones&0x0f), int(stones>>4)
+	switch {
+	case white == 0 && black < s.Row:
+		return s.Values[black]
+	case black == 0 && white < s.Row:
+		return s.Values[white]
+	}
+	return 0
+}
@@ -1,5 +0,0 @@
-//go:build size11
-
-package board
-
-const Size = 11
@@ -1,5 +0,0 @@
-//go:build !size11 && !size9
-
-package board
-
-const Size = 19
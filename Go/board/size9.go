@@ -1,5 +0,0 @@
-//go:build size9
-
-package board
-
-const Size = 9
@@ -0,0 +1,29 @@
+package board
+
+import "math/rand/v2"
+
+var zobrist = func() (table [MaxSize][MaxSize][2]uint64) {
+	rng := rand.New(rand.NewPCG(0x6d6f6e7465, 0x7a6f6272697374))
+	for y := range MaxSize {
+		for x := range MaxSize {
+			table[y][x][0] = rng.Uint64()
+			table[y][x][1] = rng.Uint64()
+		}
+	}
+	return table
+}()
+
+// computeHash recomputes the hash from the stones on the board.
+func (b *Board) computeHash() (hash uint64) {
+	for y := range b.height {
+		for x := range b.width {
+			switch b.stones[y][x] {
+			case Black:
+				hash ^= zobrist[y][x][0]
+			case White:
+				hash ^= zobrist[y][x][1]
+			}
+		}
+	}
+	return hash
+}
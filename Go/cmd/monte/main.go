@@ -0,0 +1,224 @@
+// Command monte plays Connect6 in the terminal against the engine, or lets the
+// engine play against itself with -self. With -gtp it speaks the engine
+// protocol of package gtp on stdin and stdout instead, and with -http it
+// serves the JSON analysis API of package server for all games. Gomoku and
+// Renju are selected with -game.
+//
+// Moves are entered as places separated by "-", for example "j10" for the
+// first move and "i9-i11" after that. The position after every move is printed
+// in the notation of connect.ParsePosition, and -position starts from one.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"monte/board"
+	. "monte/common"
+	"monte/connect"
+	"monte/connect6"
+	"monte/gomoku"
+	"monte/gtp"
+	"monte/renju"
+	"monte/server"
+	"monte/tree"
+)
+
+type config struct {
+	rules     connect.Rules
+	human     Turn
+	self      bool
+	position  string
+	opening   []string
+	limits    tree.Limits
+	workers   int
+	maxMoves  int
+	maxPlaces int
+}
+
+var games = map[string]connect.Rules{
+	"connect6":        connect6.Rules,
+	"gomoku":          gomoku.Freestyle,
+	"gomoku-standard": gomoku.Standard,
+	"renju":           renju.Rules,
+}
+
+func main() {
+	gameName := flag.String("game", "connect6", "game to play: connect6, gomoku, gomoku-standard or renju")
+	gtpMode := flag.Bool("gtp", false, "speak the engine protocol on stdin and stdout")
+	httpAddr := flag.String("http", "", `serve the analysis API on the address, for example ":8080"`)
+	side := flag.String("side", "black", "side of the human player: black or white")
+	self := flag.Bool("self", false, "let the engine play against itself")
+	position := flag.String("position", "", `position to start from, for example "19/.../19 w 2"`)
+	opening := flag.String("opening", "", `moves played before the game starts, for example "j10 i9-i11"`)
+	duration := flag.Duration("time", 5*time.Second, "engine time per move")
+	simulations := flag.Int("sims", 0, "engine simulations per move, 0 for no limit")
+	workers := flag.Int("workers", 1, "engine search goroutines")
+	maxMoves := flag.Int("moves", 60, "moves considered in every position")
+	maxPlaces := flag.Int("places", 32, "best places combined into the moves considered")
+	flag.Parse()
+
+	cfg := config{
+		self:      *self,
+		position:  *position,
+		opening:   strings.Fields(*opening),
+		limits:    tree.Limits{Duration: *duration, Simulations: *simulations},
+		workers:   *workers,
+		maxMoves:  *maxMoves,
+		maxPlaces: *maxPlaces,
+	}
+	rules, ok := games[*gameName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown game %q\n", *gameName)
+		os.Exit(2)
+	}
+	cfg.rules = rules
+
+	if *gtpMode {
+		engine := gtp.NewConnectEngine(rules, cfg.limits)
+		engine.SetWorkers(cfg.workers)
+		if err := gtp.NewServer(engine, "monte", "0.1").Run(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *httpAddr != "" {
+		analysis := server.NewServer(games, cfg.limits)
+		analysis.SetWorkers(cfg.workers)
+		if err := http.ListenAndServe(*httpAddr, analysis); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	switch *side {
+	case "black":
+		cfg.human = First
+	case "white":
+		cfg.human = Second
+	default:
+		fmt.Fprintf(os.Stderr, "unknown side %q\n", *side)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := play(ctx, os.Stdin, os.Stdout, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// play runs one game. It returns when the game is over, the input ends or ctx
+// is canceled, also in the middle of a search, whose move is not played then.
+func play(ctx context.Context, in io.Reader, out io.Writer, cfg config) error {
+	b := cfg.rules.MakeBoard()
+	game := connect.MakeGame(cfg.rules, cfg.maxPlaces)
+	if cfg.position != "" {
+		var err error
+		if b, game, err = connect.ParsePosition(cfg.rules, cfg.position, cfg.maxPlaces); err != nil {
+			return fmt.Errorf("position: %w", err)
+		}
+	}
+	tr := tree.NewTree[*connect.Connect, *board.Board, connect.Move](cfg.maxMoves)
+	tr.SetWorkers(cfg.workers)
+	input := bufio.NewScanner(in)
+
+	for _, moveStr := range cfg.opening {
+		move, err := connect.ParseMove(moveStr)
+		if err != nil {
+			return fmt.Errorf("opening %q: %w", moveStr, err)
+		}
+		if _, err := game.PlayMove(&b, move); err != nil {
+			return fmt.Errorf("opening: %w", err)
+		}
+		tr.CommitMove(move)
+	}
+	fmt.Fprintln(out, &b)
+	fmt.Fprintln(out, game.Position(&b))
+
+	for !b.IsOver() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		turn := game.Turn()
+		var move connect.Move
+		if !cfg.self && turn == cfg.human {
+			var ok bool
+			move, ok = humanMove(input, out, &b, &game)
+			if !ok {
+				return input.Err()
+			}
+		} else {
+			decision, stats, ok := tr.Search(ctx, &b, &game, cfg.limits)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !ok {
+				return errors.New("search found no move")
+			}
+			move = decision.Move
+			if _, err := game.PlayMove(&b, move); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%v plays %v  v: %v n: %d  (%d simulations in %v, %v)\n",
+				sideName(turn), move, decision.Value, decision.NSims, stats.Simulations, stats.Duration.Round(time.Millisecond), stats.Reason)
+		}
+		tr.CommitMove(move)
+		fmt.Fprintln(out, &b)
+		fmt.Fprintln(out, game.Position(&b))
+	}
+
+	if b.IsDraw() {
+		fmt.Fprintln(out, "Draw")
+	} else {
+		fmt.Fprintf(out, "%v wins\n", b.Winner())
+	}
+	return nil
+}
+
+// humanMove reads moves until one can be played and plays it. It returns false
+// when the input ends.
+func humanMove(input *bufio.Scanner, out io.Writer, b *board.Board, game *connect.Connect) (connect.Move, bool) {
+	for {
+		fmt.Fprintf(out, "%v to move (%d stones): ", sideName(game.Turn()), game.Stones())
+		if !input.Scan() {
+			return connect.Move{}, false
+		}
+		moveStr := strings.TrimSpace(input.Text())
+		if moveStr == "" {
+			continue
+		}
+		move, err := game.ParseMove(moveStr)
+		if err == nil {
+			_, err = game.PlayMove(b, move)
+		}
+		if err == nil {
+			return move, true
+		}
+		var moveErr *connect.MoveError
+		if !errors.As(err, &moveErr) {
+			err = fmt.Errorf("%q: %w", moveStr, err)
+		}
+		fmt.Fprintln(out, err)
+	}
+}
+
+func sideName(turn Turn) string {
+	if turn == First {
+		return "Black"
+	}
+	return "White"
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "monte/common"
+	"monte/connect6"
+	"monte/tree"
+)
+
+func TestHumanGame(t *testing.T) {
+	in := strings.NewReader("j10\nj10-k11\nx\n\na1-a2\n")
+	out := &bytes.Buffer{}
+	cfg := config{rules: connect6.Rules, human: First, limits: tree.Limits{Simulations: 10}, workers: 1, maxMoves: 8, maxPlaces: 8}
+	if err := play(context.Background(), in, out, cfg); err != nil {
+		t.Fatal(err)
+	}
+	output := out.String()
+	for _, expected := range []string{"White plays", "place occupied", "failed to parse move"} {
+		if !strings.Contains(output, expected) {
+			fmt.Println(output)
+			t.Fatalf("expected %q", expected)
+		}
+	}
+	if strings.Count(output, "White plays") != 2 {
+		fmt.Println(output)
+		t.Fail()
+	}
+}
+
+func TestSelfPlay(t *testing.T) {
+	out := &bytes.Buffer{}
+	cfg := config{
+		rules:     connect6.Rules,
+		human:     Second,
+		self:      true,
+		opening:   []string{"j10", "i9-i11"},
+		limits:    tree.Limits{Simulations: 4},
+		workers:   1,
+		maxMoves:  4,
+		maxPlaces: 4,
+	}
+	if err := play(context.Background(), strings.NewReader(""), out, cfg); err != nil {
+		fmt.Println(out)
+		t.Fatal(err)
+	}
+	output := out.String()
+	if !strings.HasSuffix(output, "wins\n") && !strings.HasSuffix(output, "Draw\n") {
+		fmt.Println(output)
+		t.Fail()
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	out := &bytes.Buffer{}
+	cfg := config{rules: connect6.Rules, human: Second, limits: tree.Limits{Duration: time.Minute}, workers: 1, maxMoves: 8, maxPlaces: 8}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := play(ctx, strings.NewReader(""), out, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "plays") {
+		fmt.Println(out)
+		t.Fail()
+	}
+}
+
+func TestPosition(t *testing.T) {
+	out := &bytes.Buffer{}
+	cfg := config{
+		rules:     connect6.Rules,
+		human:     Second,
+		position:  "19/19/19/19/19/19/19/19/8o1o8/9x9/19/19/19/19/19/19/19/19/19 b 2",
+		opening:   []string{"k11-l12"},
+		limits:    tree.Limits{Simulations: 4},
+		workers:   1,
+		maxMoves:  4,
+		maxPlaces: 4,
+	}
+	if err := play(context.Background(), strings.NewReader(""), out, cfg); err != nil {
+		fmt.Println(out)
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "19/19/19/19/19/19/19/19/8o1o8/9x9/10x8/11x7/19/19/19/19/19/19/19 w 2\n") {
+		fmt.Println(out)
+		t.Fail()
+	}
+
+	cfg.position = "19 w 2"
+	if err := play(context.Background(), strings.NewReader(""), out, cfg); err == nil {
+		t.Fail()
+	}
+}
@@ -79,3 +79,21 @@ func (value Value) String() string {
 	}
 	return fmt.Sprint(float32(value))
 }
+
+// Result is the outcome of playing a move.
+type Result int
+
+const (
+	Continue Result = iota
+	Won
+)
+
+func (result Result) String() string {
+	switch result {
+	case Continue:
+		return "continue"
+	case Won:
+		return "won"
+	}
+	panic("Result.String()")
+}
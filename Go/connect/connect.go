@@ -0,0 +1,539 @@
+// Package connect implements Connect(m,n,k,p,q) games: on a board of m by n
+// places the players take turns placing p stones, except for the first move
+// with q stones, and the first player with k stones in a row wins.
+package connect
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+
+	"monte/board"
+	. "monte/common"
+	"monte/heap"
+)
+
+// MaxStones is the largest number of stones placed in one move.
+const MaxStones = 3
+
+// Rules select a variant. Scoring rates the places for TopMoves and the
+// rollouts; its Row has to be Row. With Renju set the first player may not
+// play the forbidden moves of board.Forbidden, which only exist for five in a
+// row with single stones, and Scoring has to be a Renju scoring.
+type Rules struct {
+	Width, Height int
+	Row           int
+	Stones        int
+	FirstStones   int
+	Scoring       *board.Scoring
+	Renju         bool
+}
+
+var (
+	ErrGameOver       = errors.New("game over")
+	ErrStoneCount     = errors.New("wrong number of stones")
+	ErrOutOfBounds    = errors.New("place out of bounds")
+	ErrOccupied       = errors.New("place occupied")
+	ErrDuplicatePlace = errors.New("duplicate place")
+	ErrForbidden      = errors.New("forbidden move")
+)
+
+// MoveError is returned by PlayMove for a move that cannot be played. Err is
+// one of the errors above.
+type MoveError struct {
+	Move Move
+	Err  error
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("move %v: %v", e.Move, e.Err)
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNoMove is returned by UndoMove when there is no move to take back.
+var ErrNoMove = errors.New("no move to undo")
+
+// NewRules returns the rules of Connect(width, height, row, stones,
+// firstStones) with the default scoring for rows of row places, which has
+// to be at most board.MaxDefaultRow. Longer rows need a Scoring of their own.
+func NewRules(width, height, row, stones, firstStones int) Rules {
+	return Rules{
+		Width:       width,
+		Height:      height,
+		Row:         row,
+		Stones:      stones,
+		FirstStones: firstStones,
+		Scoring:     board.DefaultScoring(row),
+	}
+}
+
+func (r Rules) String() string {
+	return fmt.Sprintf("Connect(%d,%d,%d,%d,%d)", r.Width, r.Height, r.Row, r.Stones, r.FirstStones)
+}
+
+func (r Rules) validate() {
+	if r.Row != r.Scoring.Row ||
+		r.Stones < 1 || r.Stones > MaxStones ||
+		r.FirstStones < 1 || r.FirstStones > MaxStones ||
+		r.Renju && (r.Row != 5 || r.Stones != 1 || r.FirstStones != 1 || !r.Scoring.Renju) {
+		panic(fmt.Sprintf("%v: unsupported rules", r))
+	}
+}
+
+func (r Rules) MakeBoard() board.Board {
+	r.validate()
+	return board.MakeBoard(r.Width, r.Height, r.Scoring)
+}
+
+type Place struct {
+	X, Y int8
+}
+
+func (p Place) String() string {
+	return fmt.Sprintf("%c%d", p.X+'a', p.Y+1)
+}
+
+// Move holds the places of its stones in a canonical order, so that moves
+// with the same places are equal whatever order they were given in.
+type Move struct {
+	places [MaxStones]Place
+	n      int8
+}
+
+// MakeMove returns the move placing stones on the places. Repeated places are
+// kept so that PlayMove can reject them.
+func MakeMove(places ...Place) Move {
+	if len(places) > MaxStones {
+		panic(fmt.Sprintf("MakeMove(%v): too many places", places))
+	}
+	m := Move{}
+	for _, place := range places {
+		i := int8(0)
+		for i < m.n && m.places[i].before(place) {
+			i++
+		}
+		copy(m.places[i+1:], m.places[i:m.n])
+		m.places[i] = place
+		m.n++
+	}
+	return m
+}
+
+// before orders places by x ascending and then by y descending.
+func (p Place) before(other Place) bool {
+	return p.X < other.X || p.X == other.X && p.Y > other.Y
+}
+
+func (m Move) Places() []Place {
+	return m.places[:m.n]
+}
+
+func (m Move) Equal(other Move) bool {
+	return m == other
+}
+
+func (m Move) String() string {
+	places := make([]string, m.n)
+	for i, place := range m.Places() {
+		places[i] = place.String()
+	}
+	return strings.Join(places, "-")
+}
+
+func ParseMove(moveStr string) (Move, error) {
+	tokens := strings.Split(moveStr, "-")
+	if len(tokens) > MaxStones {
+		return Move{}, errors.New("failed to parse move")
+	}
+	places := make([]Place, len(tokens))
+	for i, token := range tokens {
+		x, y, err := board.ParsePlace(token)
+		if err != nil {
+			return Move{}, errors.New("failed to parse move")
+		}
+		places[i] = Place{x, y}
+	}
+	return MakeMove(places...), nil
+}
+
+type Connect struct {
+	rules    Rules
+	turn     Turn
+	moves    int
+	rollouts board.Rollout
+	places   []board.Place
+	history  []Move
+}
+
+func MakeGame(rules Rules, maxPlaces int) Connect {
+	rules.validate()
+	game := Connect{
+		rules:    rules,
+		turn:     First,
+		rollouts: board.DefaultRollout,
+		places:   make([]board.Place, 0, maxPlaces),
+	}
+	return game
+}
+
+// Copy returns a copy of the game. A random rollout policy gets its own
+// generator seeded from the generator of the game, so that copies can roll
+// out concurrently.
+func (c *Connect) Copy() *Connect {
+	rollouts := c.rollouts
+	if rollouts.Rand != nil {
+		rollouts.Rand = rand.New(rand.NewPCG(rollouts.Rand.Uint64(), rollouts.Rand.Uint64()))
+	}
+	return &Connect{
+		rules:    c.rules,
+		turn:     c.turn,
+		moves:    c.moves,
+		rollouts: rollouts,
+		places:   make([]board.Place, 0, cap(c.places)),
+		history:  slices.Clone(c.history),
+	}
+}
+
+// SetRollout sets how TopMoves values moves, board.DefaultRollout by default.
+// Without a generator the rollouts get one seeded from entropy.
+func (c *Connect) SetRollout(rollout board.Rollout) {
+	c.rollouts = rollout
+	c.SetRand(rollout.Rand)
+}
+
+// SetRand sets the generator of the rollout policy, or one seeded from
+// entropy for nil.
+func (c *Connect) SetRand(rng *rand.Rand) {
+	if rng == nil {
+		rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	c.rollouts.Rand = rng
+}
+
+func (c *Connect) Rules() Rules {
+	return c.rules
+}
+
+func (c *Connect) Turn() Turn {
+	return c.turn
+}
+
+// Stones returns the number of stones of the next move.
+func (c *Connect) Stones() int {
+	if c.moves == 0 {
+		return c.rules.FirstStones
+	}
+	return c.rules.Stones
+}
+
+func (c *Connect) ExpFactor() float64 {
+	return 1
+}
+
+func (c *Connect) ParseMove(moveStr string) (Move, error) {
+	return ParseMove(moveStr)
+}
+
+// Position returns the position in the notation of board.Position followed by
+// the side to move, "b" or "w", and the number of stones left in its turn, as
+// in "3/1x1/3 w 2".
+func (c *Connect) Position(b *board.Board) string {
+	side := "b"
+	if c.turn == Second {
+		side = "w"
+	}
+	return fmt.Sprintf("%s %s %d", b.Position(), side, c.Stones())
+}
+
+// ParsePosition returns the board and the game of a position written by
+// Position. The size of the board replaces the size of the rules. The numbers
+// of black and white stones have to be those of a game with the side to move;
+// only the last move of a game that is over may have fewer stones. A position
+// in the middle of a turn, with fewer stones left than a move places, is
+// rejected since moves are played whole.
+func ParsePosition(rules Rules, position string, maxPlaces int) (board.Board, Connect, error) {
+	fields := strings.Fields(position)
+	if len(fields) != 3 {
+		return board.Board{}, Connect{}, errors.New("failed to parse position")
+	}
+	b, err := board.ParsePosition(fields[0], rules.Scoring)
+	if err != nil {
+		return board.Board{}, Connect{}, err
+	}
+	rules.Width, rules.Height = b.Width(), b.Height()
+	game := MakeGame(rules, maxPlaces)
+	switch fields[1] {
+	case "b":
+	case "w":
+		game.turn = Second
+	default:
+		return board.Board{}, Connect{}, errors.New("failed to parse position")
+	}
+	stones, err := strconv.Atoi(fields[2])
+	if err != nil || stones < 1 {
+		return board.Board{}, Connect{}, errors.New("failed to parse position")
+	}
+	black, white := count(&b, board.Black), count(&b, board.White)
+	if stones < rules.Stones && !(black+white == 0 && stones == rules.FirstStones) {
+		return board.Board{}, Connect{}, errors.New("position in the middle of a move")
+	}
+	moves, ok := rules.moves(black, white, game.turn, b.IsOver())
+	if !ok {
+		return board.Board{}, Connect{}, errors.New("stones do not match the side to move")
+	}
+	game.moves = moves
+	if stones != game.Stones() {
+		return board.Board{}, Connect{}, errors.New("stones to place do not match the rules")
+	}
+	return b, game, nil
+}
+
+// moves returns the number of moves that leave black and white stones on the
+// board with turn to move. The last move may be short when the game is over.
+func (r Rules) moves(black, white int, turn Turn, over bool) (int, bool) {
+	for moves := int(turn); moves <= black+white; moves += 2 {
+		wantBlack, wantWhite := r.stones(moves)
+		if black == wantBlack && white == wantWhite {
+			return moves, true
+		}
+		if !over || moves == 0 {
+			continue
+		}
+		lastBlack, lastWhite := r.stones(moves - 1)
+		if turn == Second && white == wantWhite && black > lastBlack && black < wantBlack ||
+			turn == First && black == wantBlack && white > lastWhite && white < wantWhite {
+			return moves, true
+		}
+	}
+	return 0, false
+}
+
+// stones returns the numbers of black and white stones after moves moves.
+func (r Rules) stones(moves int) (int, int) {
+	if moves == 0 {
+		return 0, 0
+	}
+	return r.FirstStones + (moves-1)/2*r.Stones, moves / 2 * r.Stones
+}
+
+// PlayMove places the stones of the move and passes the turn to the other
+// player. A winning stone ends the game and the remaining stones of the move
+// are not placed. A move that cannot be played is rejected with a *MoveError
+// and leaves the game unchanged.
+func (c *Connect) PlayMove(board *board.Board, move Move) (Result, error) {
+	if err := c.validate(board, move); err != nil {
+		return Continue, &MoveError{Move: move, Err: err}
+	}
+	defer c.nextTurn()
+	places := move.Places()
+	for i, place := range places {
+		if board.PlaceStone(c.turn, int(place.X), int(place.Y)) {
+			c.history = append(c.history, MakeMove(places[:i+1]...))
+			return Won, nil
+		}
+	}
+	c.history = append(c.history, move)
+	return Continue, nil
+}
+
+// PlayMoves parses and plays the moves one after another and returns the
+// result of the last one. It stops at the first move that fails to parse or
+// to play.
+func (c *Connect) PlayMoves(b *board.Board, moveStrs ...string) (Result, error) {
+	result := Continue
+	for _, moveStr := range moveStrs {
+		move, err := ParseMove(moveStr)
+		if err != nil {
+			return result, err
+		}
+		if result, err = c.PlayMove(b, move); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (c *Connect) validate(b *board.Board, move Move) error {
+	if b.IsOver() {
+		return ErrGameOver
+	}
+	places := move.Places()
+	if stones := c.Stones(); len(places) != stones && !(len(places) < stones && len(places) == empty(b)) {
+		return ErrStoneCount
+	}
+	for i, place := range places {
+		if !b.Contains(int(place.X), int(place.Y)) {
+			return ErrOutOfBounds
+		}
+		if b.Stone(place.X, place.Y) != board.None {
+			return ErrOccupied
+		}
+		if i > 0 && place == places[i-1] {
+			return ErrDuplicatePlace
+		}
+	}
+	if c.rules.Renju && c.turn == First {
+		for _, place := range places {
+			if b.Forbidden(int(place.X), int(place.Y)) {
+				return ErrForbidden
+			}
+		}
+	}
+	return nil
+}
+
+// count returns the number of stones of a color on the board.
+func count(b *board.Board, stone board.Stone) int {
+	n := 0
+	for y := range int8(b.Height()) {
+		for x := range int8(b.Width()) {
+			if b.Stone(x, y) == stone {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// empty returns the number of empty places, which limits the stones of the
+// last move.
+func empty(b *board.Board) int {
+	n := 0
+	for y := range int8(b.Height()) {
+		for x := range int8(b.Width()) {
+			if b.Stone(x, y) == board.None {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// UndoMove takes back the last move played by PlayMove on the board and
+// returns it. Only the stones that were placed are removed, which are fewer
+// than those of the move when it won. It returns ErrNoMove when no move was
+// played since MakeGame or ParsePosition.
+func (c *Connect) UndoMove(b *board.Board) (Move, error) {
+	if len(c.history) == 0 {
+		return Move{}, ErrNoMove
+	}
+	move := c.history[len(c.history)-1]
+	c.history = c.history[:len(c.history)-1]
+	c.flipTurn()
+	c.moves--
+	places := move.Places()
+	for i := len(places) - 1; i >= 0; i-- {
+		b.RemoveStone(int(places[i].X), int(places[i].Y))
+	}
+	return move, nil
+}
+
+// History returns the moves played by PlayMove since MakeGame or
+// ParsePosition, the stones placed by a winning move only.
+func (c *Connect) History() []Move {
+	return slices.Clone(c.history)
+}
+
+func (c *Connect) nextTurn() {
+	c.flipTurn()
+	c.moves++
+}
+
+func (c *Connect) flipTurn() {
+	if c.turn == First {
+		c.turn = Second
+	} else {
+		c.turn = First
+	}
+}
+
+// TopMoves rates every combination of the top places by the sum of their
+// scores and values the best ones with a rollout. When no combination scores
+// anything the game is a draw. Forbidden places are left out.
+func (c *Connect) TopMoves(board *board.Board, moves *[]MoveValue[Move]) {
+	*moves = (*moves)[:0]
+	drawMove := Move{}
+	hasDraw := false
+
+	board.TopPlaces(&c.places)
+	if c.rules.Renju && c.turn == First {
+		places := c.places[:0]
+		for _, place := range c.places {
+			if !board.Forbidden(int(place.X), int(place.Y)) {
+				places = append(places, place)
+			}
+		}
+		c.places = places
+	}
+
+	stones := min(c.Stones(), len(c.places))
+	idxs := [MaxStones]int{}
+	for i := range stones {
+		idxs[i] = i
+	}
+	for {
+		places := [MaxStones]Place{}
+		score := 0
+		for i, idx := range idxs[:stones] {
+			place := c.places[idx]
+			places[i] = Place{place.X, place.Y}
+			score += int(board.Score(place.X, place.Y))
+		}
+		move := MakeMove(places[:stones]...)
+		if score == 0 {
+			if !hasDraw {
+				drawMove = move
+				hasDraw = true
+			}
+		} else {
+			heap.Add(moves, MoveValue[Move]{Move: move, Value: Value(score)})
+		}
+
+		if !nextCombination(idxs[:stones], len(c.places)) {
+			break
+		}
+	}
+	for i := range *moves {
+		(*moves)[i].Value = c.rollout(board, (*moves)[i].Move)
+	}
+
+	if len(*moves) == 0 {
+		*moves = append(*moves, MoveValue[Move]{Move: drawMove, Value: Draw})
+	}
+}
+
+// nextCombination advances the increasing indices in idxs to the next
+// combination of indices below n.
+func nextCombination(idxs []int, n int) bool {
+	for i := len(idxs) - 1; i >= 0; i-- {
+		if idxs[i] < n-len(idxs)+i {
+			idxs[i]++
+			for j := i + 1; j < len(idxs); j++ {
+				idxs[j] = idxs[j-1] + 1
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// rollout values a move by a rollout after it. The moves come from TopMoves,
+// so a rejected one is a bug.
+func (c *Connect) rollout(board *board.Board, move Move) Value {
+	copy := board.Copy()
+	game := Connect{rules: c.rules, turn: c.turn, moves: c.moves}
+	result, err := game.PlayMove(copy, move)
+	if err != nil {
+		panic(fmt.Sprintf("rollout(%v): %v", move, err))
+	}
+	if result == Won {
+		return Win
+	}
+	return -copy.Rollout(game.turn, game.rules.Stones, c.rollouts)
+}
@@ -0,0 +1,314 @@
+package connect
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"testing"
+
+	"monte/board"
+	"monte/common"
+)
+
+var connect6 = Rules{
+	Width:       19,
+	Height:      19,
+	Row:         6,
+	Stones:      2,
+	FirstStones: 1,
+	Scoring:     board.Connect6Scoring,
+}
+
+func parseMoves(t *testing.T, moveStrs ...string) []Move {
+	moves := make([]Move, len(moveStrs))
+	for i, moveStr := range moveStrs {
+		move, err := ParseMove(moveStr)
+		if err != nil {
+			t.Fatal(moveStr, err)
+		}
+		moves[i] = move
+	}
+	return moves
+}
+
+func TestMakeMove(t *testing.T) {
+	move := MakeMove(Place{3, 4}, Place{1, 2}, Place{3, 7})
+	if move != MakeMove(Place{3, 7}, Place{3, 4}, Place{1, 2}) {
+		t.Fail()
+	}
+	if move.String() != "b3-d8-d5" {
+		fmt.Println(move)
+		t.Fail()
+	}
+	if len(MakeMove(Place{1, 1}, Place{1, 1}).Places()) != 2 {
+		t.Fail()
+	}
+	parsed, err := ParseMove("d5-b3-d8")
+	if err != nil || parsed != move {
+		fmt.Println(parsed, err)
+		t.Fail()
+	}
+	if _, err := ParseMove("a1-a2-a3-a4"); err == nil {
+		t.Fail()
+	}
+}
+
+func TestNextCombination(t *testing.T) {
+	idxs := []int{0, 1}
+	n := 1
+	for nextCombination(idxs, 5) {
+		n++
+	}
+	if n != 10 {
+		fmt.Println("expected 10 combinations got", n)
+		t.Fail()
+	}
+}
+
+func TestPlayMoveErrors(t *testing.T) {
+	b := connect6.MakeBoard()
+	game := MakeGame(connect6, 20)
+	tests := []struct {
+		move Move
+		err  error
+	}{
+		{MakeMove(Place{9, 9}, Place{9, 10}), ErrStoneCount},
+		{MakeMove(Place{19, 9}), ErrOutOfBounds},
+		{MakeMove(Place{9, -1}), ErrOutOfBounds},
+		{MakeMove(Place{9, 9}), nil},
+		{MakeMove(Place{8, 8}), ErrStoneCount},
+		{MakeMove(Place{8, 8}, Place{9, 9}), ErrOccupied},
+		{MakeMove(Place{8, 8}, Place{8, 8}), ErrDuplicatePlace},
+		{MakeMove(Place{8, 8}, Place{8, 10}), nil},
+	}
+	for _, test := range tests {
+		before, turn := b, game.turn
+		_, err := game.PlayMove(&b, test.move)
+		if !errors.Is(err, test.err) {
+			fmt.Println(test.move, "expected", test.err, "got", err)
+			t.Fail()
+		}
+		var moveErr *MoveError
+		if test.err != nil && (!errors.As(err, &moveErr) || moveErr.Move != test.move || b != before || game.turn != turn) {
+			fmt.Println(test.move, "changed the game")
+			t.Fail()
+		}
+	}
+}
+
+func TestRollout(t *testing.T) {
+	board := connect6.MakeBoard()
+	game := MakeGame(connect6, 20)
+	moves := parseMoves(t, "j10", "i9-i11")
+	game.PlayMove(&board, moves[0])
+	rolloutScore := game.rollout(&board, moves[1])
+	fmt.Println(rolloutScore)
+}
+
+func TestSetRollout(t *testing.T) {
+	topMoves := func(seed uint64) []common.MoveValue[Move] {
+		b := connect6.MakeBoard()
+		game := MakeGame(connect6, 10)
+		game.SetRollout(board.Rollout{Policy: board.TopK{K: 4}, Rand: rand.New(rand.NewPCG(seed, 0))})
+		for _, move := range parseMoves(t, "j10", "i9-i11") {
+			game.PlayMove(&b, move)
+		}
+		moves := make([]common.MoveValue[Move], 0, 10)
+		game.Copy().TopMoves(&b, &moves)
+		return moves
+	}
+	if !slices.Equal(topMoves(1), topMoves(1)) {
+		t.Fail()
+	}
+	differ := false
+	for seed := range uint64(10) {
+		differ = differ || !slices.Equal(topMoves(seed), topMoves(seed+1))
+	}
+	if !differ {
+		fmt.Println("rollouts do not depend on the seed")
+		t.Fail()
+	}
+
+	// A random policy without a generator gets one.
+	b := connect6.MakeBoard()
+	game := MakeGame(connect6, 10)
+	game.SetRollout(board.Rollout{Policy: board.TopK{K: 3}})
+	game.PlayMove(&b, parseMoves(t, "j10")[0])
+	moves := make([]common.MoveValue[Move], 0, 10)
+	game.Copy().TopMoves(&b, &moves)
+	if len(moves) == 0 {
+		t.Fail()
+	}
+}
+
+type byScore []common.MoveValue[Move]
+
+func (b byScore) Len() int {
+	return len(b)
+}
+
+func (b byScore) Less(i, j int) bool {
+	return b[i].Value > b[j].Value
+}
+
+func (b byScore) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func TestTopMoves(t *testing.T) {
+	board := connect6.MakeBoard()
+	game := MakeGame(connect6, 20)
+	moves := make([]common.MoveValue[Move], 0, 60)
+
+	for _, move := range parseMoves(t, "j10", "i9-i11") {
+		game.PlayMove(&board, move)
+	}
+	game.TopMoves(&board, &moves)
+
+	sort.Sort(byScore(moves))
+
+	fmt.Println(&board)
+	for i, move := range moves {
+		fmt.Printf("%3d %v\n", i+1, move)
+		if len(move.Move.Places()) != 2 {
+			t.Fail()
+		}
+	}
+}
+
+func TestGomoku(t *testing.T) {
+	rules := NewRules(15, 15, 5, 1, 1)
+	b := rules.MakeBoard()
+	game := MakeGame(rules, 10)
+	moves := make([]common.MoveValue[Move], 0, 10)
+
+	game.TopMoves(&b, &moves)
+	for _, move := range moves {
+		if len(move.Move.Places()) != 1 {
+			fmt.Println(move)
+			t.Fail()
+		}
+	}
+
+	for _, move := range parseMoves(t, "h8", "a1", "h9", "a2", "h10", "a3", "h11", "a4") {
+		if result, _ := game.PlayMove(&b, move); result == common.Won {
+			t.Fatal("unexpected win", move)
+		}
+	}
+	if result, _ := game.PlayMove(&b, parseMoves(t, "h12")[0]); result != common.Won {
+		t.Fatal("expected a win")
+	}
+}
+
+func TestUndoMove(t *testing.T) {
+	b := connect6.MakeBoard()
+	game := MakeGame(connect6, 20)
+	moves := parseMoves(t, "j10", "i9-i11", "k11-l12", "h8-h9")
+	boards := []board.Board{b}
+	games := []Connect{game}
+	for _, move := range moves {
+		game.PlayMove(&b, move)
+		boards = append(boards, b)
+		games = append(games, game)
+	}
+	if history := game.History(); !slices.Equal(history, moves) {
+		fmt.Println("history", history)
+		t.Fail()
+	}
+	for i := len(moves) - 1; i >= 0; i-- {
+		move, err := game.UndoMove(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if move != moves[i] || b != boards[i] || game.turn != games[i].turn || game.moves != games[i].moves {
+			t.Fatalf("undo %v got %v", moves[i], move)
+		}
+	}
+	if _, err := game.UndoMove(&b); !errors.Is(err, ErrNoMove) || b != boards[0] {
+		fmt.Println("undo", err)
+		t.Fail()
+	}
+
+	for _, move := range parseMoves(t, "j10", "a1-a2", "j11-j12", "a3-a4", "j13-j14", "b1-b2") {
+		game.PlayMove(&b, move)
+	}
+	before := b
+	if result, _ := game.PlayMove(&b, parseMoves(t, "j15-s1")[0]); result != common.Won {
+		t.Fatal("expected a win")
+	}
+	if _, err := game.PlayMove(&b, parseMoves(t, "c2-c3")[0]); !errors.Is(err, ErrGameOver) {
+		fmt.Println("expected game over got", err)
+		t.Fail()
+	}
+	// Only the winning stone was placed.
+	if move, err := game.UndoMove(&b); err != nil || move.String() != "j15" || b != before || game.turn != common.First {
+		fmt.Println("undo", move, err)
+		t.Fail()
+	}
+}
+
+func BenchmarkTopMoves(b *testing.B) {
+	board := connect6.MakeBoard()
+	game := MakeGame(connect6, 30)
+	moves := make([]common.MoveValue[Move], 0, 30)
+	for _, move := range []Move{MakeMove(Place{9, 9}), MakeMove(Place{8, 8}, Place{8, 10})} {
+		game.PlayMove(&board, move)
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		game.TopMoves(&board, &moves)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	b := connect6.MakeBoard()
+	game := MakeGame(connect6, 20)
+	if position := game.Position(&b); position != "19/19/19/19/19/19/19/19/19/19/19/19/19/19/19/19/19/19/19 b 1" {
+		fmt.Println(position)
+		t.Fail()
+	}
+	for _, move := range parseMoves(t, "j10", "i9-i11", "k11-l12") {
+		game.PlayMove(&b, move)
+	}
+	position := game.Position(&b)
+	parsedBoard, parsed, err := ParsePosition(connect6, position, 20)
+	if err != nil || parsedBoard != b || parsed.turn != game.turn || parsed.moves != game.moves {
+		fmt.Println(position, err, parsed.turn, parsed.moves)
+		t.Fail()
+	}
+	if parsed.Position(&parsedBoard) != position {
+		fmt.Println(parsed.Position(&parsedBoard))
+		t.Fail()
+	}
+
+	small, smallGame, err := ParsePosition(connect6, "3/1x1/3 w 2", 20)
+	if err != nil || small.Width() != 3 || smallGame.Rules().Width != 3 || smallGame.Turn() != common.Second {
+		fmt.Println(err)
+		t.Fail()
+	}
+	for _, position := range []string{"3/1x1/3", "3/1x1/3 x 2", "3/1x1/3 w two", "3/1x1/3 w 3", "3/1x1/3 w 2 1",
+		"3/1x1/3 b 2", "xx1/3/3 b 2", "xx1/3/3 w 2", "xo1/3/3 w 2", "3/3/3 b 2"} {
+		if _, _, err := ParsePosition(connect6, position, 20); err == nil {
+			fmt.Println("expected an error for", position)
+			t.Fail()
+		}
+	}
+	if _, _, err := ParsePosition(connect6, "xo1/3/3 w 1", 20); err == nil || err.Error() != "position in the middle of a move" {
+		fmt.Println(err)
+		t.Fail()
+	}
+
+	b, game = connect6.MakeBoard(), MakeGame(connect6, 20)
+	for _, move := range parseMoves(t, "j10", "a1-a2", "j11-j12", "a3-a4", "j13-j14", "b1-b2", "j15-j9") {
+		game.PlayMove(&b, move)
+	}
+	position = game.Position(&b)
+	parsedBoard, parsed, err = ParsePosition(connect6, position, 20)
+	if err != nil || parsedBoard != b || parsed.turn != game.turn || parsed.moves != game.moves {
+		fmt.Println(position, err, parsed.moves, game.moves)
+		t.Fail()
+	}
+}
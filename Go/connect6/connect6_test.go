@@ -1,63 +1,76 @@
 package connect6
 
 import (
+	"context"
 	"fmt"
-	"sort"
 	"testing"
 
 	"monte/board"
 	"monte/common"
+	"monte/tree"
 )
 
-func TestRollout(t *testing.T) {
-	board := board.MakeBoard()
+func TestPlayMove(t *testing.T) {
+	b := MakeBoard()
 	game := MakeGame(20)
-	game.PlayMove(&board, Move{9, 9, 9, 9})
-	rolloutScore := game.rollout(&board, Move{8, 8, 8, 10})
-	fmt.Println(rolloutScore)
-}
-
-type byScore []common.MoveValue[Move]
-
-func (b byScore) Len() int {
-	return len(b)
-}
-
-func (b byScore) Less(i, j int) bool {
-	return b[i].Value > b[j].Value
-}
-
-func (b byScore) Swap(i, j int) {
-	b[i], b[j] = b[j], b[i]
+	for _, moveStr := range []string{"j10", "i9-i11", "k11-l12"} {
+		move, err := ParseMove(moveStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if game.Stones() != len(move.Places()) {
+			fmt.Println(moveStr, "expected", game.Stones(), "stones")
+			t.Fail()
+		}
+		if result, _ := game.PlayMove(&b, move); result == common.Won {
+			t.Fatal("unexpected win")
+		}
+	}
+	if game.Turn() != common.Second {
+		t.Fail()
+	}
 }
 
-func TestTopMoves(t *testing.T) {
-	board := board.MakeBoard()
-	game := MakeGame(20)
-	moves := make([]common.MoveValue[Move], 0, 60)
-
-	game.PlayMove(&board, Move{9, 9, 9, 9})
-	game.PlayMove(&board, Move{8, 8, 8, 10})
-	fmt.Println(&board)
-	game.TopMoves(&board, &moves)
-
-	sort.Sort(byScore(moves))
+func TestSearch(t *testing.T) {
+	b := MakeBoard()
+	game := MakeGame(8)
+	first, _ := ParseMove("j10")
+	game.PlayMove(&b, first)
 
-	fmt.Println(&board)
-	for i, move := range moves {
-		fmt.Printf("%3d %v\n", i+1, move)
+	tr := tree.NewTree[*Connect6, *board.Board, Move](8)
+	decision, _, _ := tr.Search(context.Background(), &b, &game, tree.Limits{Simulations: 20})
+	if len(decision.Move.Places()) != 2 {
+		fmt.Println("decision", decision)
+		t.Fail()
 	}
 }
 
-func BenchmarkTopMoves(b *testing.B) {
-	board := board.MakeBoard()
-	game := MakeGame(30)
-	moves := make([]common.MoveValue[Move], 0, 30)
-	game.PlayMove(&board, Move{9, 9, 9, 9})
-	game.PlayMove(&board, Move{8, 8, 8, 10})
+func TestSeededSearch(t *testing.T) {
+	search := func(seed uint64) (string, tree.Decision[Move]) {
+		b := MakeBoard()
+		game := MakeGame(8)
+		game.SetRollout(board.Rollout{Policy: board.EpsilonGreedy{Epsilon: 0.3}, Depth: 60})
+		first, _ := ParseMove("j10")
+		game.PlayMove(&b, first)
 
-	b.ResetTimer()
-	for range b.N {
-		game.TopMoves(&board, &moves)
+		tr := tree.NewTree[*Connect6, *board.Board, Move](8)
+		tr.SetSeed(seed)
+		decision, _, _ := tr.Search(context.Background(), &b, &game, tree.Limits{Simulations: 30})
+		return tr.String(), decision
+	}
+	tree1, decision1 := search(42)
+	tree2, decision2 := search(42)
+	if tree1 != tree2 || decision1 != decision2 {
+		fmt.Println(decision1, decision2)
+		t.Fail()
+	}
+	differ := false
+	for seed := range uint64(5) {
+		tree3, _ := search(seed)
+		differ = differ || tree3 != tree1
+	}
+	if !differ {
+		fmt.Println("the search does not depend on the seed")
+		t.Fail()
 	}
 }
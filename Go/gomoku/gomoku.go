@@ -0,0 +1,54 @@
+// Package gomoku holds the Gomoku presets of the connect package on a 15x15
+// board: Freestyle, where five or more stones in a row win, and Standard,
+// where only exactly five stones in a row win.
+package gomoku
+
+import (
+	"monte/board"
+	"monte/connect"
+)
+
+type (
+	Gomoku = connect.Connect
+	Move   = connect.Move
+)
+
+var (
+	Freestyle = connect.Rules{
+		Width:       15,
+		Height:      15,
+		Row:         5,
+		Stones:      1,
+		FirstStones: 1,
+		Scoring:     board.GomokuScoring,
+	}
+	Standard = connect.Rules{
+		Width:       15,
+		Height:      15,
+		Row:         5,
+		Stones:      1,
+		FirstStones: 1,
+		Scoring:     board.StandardGomokuScoring,
+	}
+)
+
+func MakeBoard(rules connect.Rules) board.Board {
+	return rules.MakeBoard()
+}
+
+func MakeGame(rules connect.Rules, maxPlaces int) Gomoku {
+	return connect.MakeGame(rules, maxPlaces)
+}
+
+func ExpFactor() float64 {
+	return 1
+}
+
+func ParseMove(moveStr string) (Move, error) {
+	return connect.ParseMove(moveStr)
+}
+
+// ParsePosition parses a position written by Gomoku.Position.
+func ParsePosition(rules connect.Rules, position string, maxPlaces int) (board.Board, Gomoku, error) {
+	return connect.ParsePosition(rules, position, maxPlaces)
+}
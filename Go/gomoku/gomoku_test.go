@@ -0,0 +1,97 @@
+package gomoku
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"monte/board"
+	"monte/common"
+	"monte/connect"
+	"monte/tree"
+)
+
+func TestOverline(t *testing.T) {
+	for _, rules := range []connect.Rules{Freestyle, Standard} {
+		b := MakeBoard(rules)
+		game := MakeGame(rules, 10)
+		if result, err := game.PlayMoves(&b, "d8", "a1", "e8", "a2", "g8", "a3", "h8", "a5", "i8", "b1"); result == common.Won || err != nil {
+			t.Fatal("unexpected win", err)
+		}
+		if result, _ := game.PlayMoves(&b, "f8"); (result == common.Won) != (rules == Freestyle) {
+			fmt.Println(rules.Scoring.Exact, "overline")
+			t.Fail()
+		}
+		if result, _ := game.PlayMoves(&b, "a4"); rules == Standard && result != common.Won {
+			fmt.Println("expected exactly five to win")
+			t.Fail()
+		}
+	}
+}
+
+func TestTopMoves(t *testing.T) {
+	b := MakeBoard(Freestyle)
+	game := MakeGame(Freestyle, 10)
+	moves := make([]common.MoveValue[Move], 0, 10)
+	if _, err := game.PlayMoves(&b, "h8", "g7"); err != nil {
+		t.Fatal(err)
+	}
+	game.TopMoves(&b, &moves)
+	if len(moves) != 10 {
+		t.Fail()
+	}
+	for _, move := range moves {
+		if len(move.Move.Places()) != 1 {
+			fmt.Println(move)
+			t.Fail()
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	b := MakeBoard(Standard)
+	game := MakeGame(Standard, 8)
+	// Black has an open four on row 8 and wins whatever White does.
+	if _, err := game.PlayMoves(&b, "e8", "e9", "f8", "f9", "g8", "g9", "h8", "a1"); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tree.NewTree[*Gomoku, *board.Board, Move](8)
+	decision, stats, _ := tr.Search(context.Background(), &b, &game, tree.Limits{Simulations: 200})
+	if !decision.Value.IsWin() {
+		fmt.Println(decision, stats.Reason)
+		t.Fail()
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	for range b.N {
+		tr := tree.NewTree[*Gomoku, *board.Board, Move](10)
+		board := MakeBoard(Freestyle)
+		game := MakeGame(Freestyle, 10)
+		move, _ := ParseMove("h8")
+		game.PlayMove(&board, move)
+		tr.Search(context.Background(), &board, &game, tree.Limits{Simulations: 100})
+	}
+}
+
+func TestTranspositions(t *testing.T) {
+	// Decided children reached through another path must still decide the node.
+	b := MakeBoard(Standard)
+	game := MakeGame(Standard, 8)
+	if _, err := game.PlayMoves(&b, "h8"); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tree.NewTree[*Gomoku, *board.Board, Move](8)
+	tr.SetTranspositions(true)
+	for range 4000 {
+		if !tr.Expand(&b, &game) {
+			break
+		}
+	}
+	if err := tr.Validate(); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+}
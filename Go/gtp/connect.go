@@ -0,0 +1,149 @@
+package gtp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"monte/board"
+	. "monte/common"
+	"monte/connect"
+	"monte/tree"
+)
+
+// ConnectEngine plays a connect game, such as Connect6 or Gomoku, with a
+// search tree.
+type ConnectEngine struct {
+	rules   connect.Rules
+	limits  tree.Limits
+	workers int
+	board   board.Board
+	game    connect.Connect
+	tree    *tree.Tree[*connect.Connect, *board.Board, connect.Move]
+
+	mainTime      time.Duration
+	byoYomiTime   time.Duration
+	byoYomiStones int
+}
+
+const (
+	maxMoves  = 60
+	maxPlaces = 32
+	maxUndos  = 1
+)
+
+// NewConnectEngine returns an engine searching with limits, unless the time
+// settings bound the time per move.
+func NewConnectEngine(rules connect.Rules, limits tree.Limits) *ConnectEngine {
+	engine := &ConnectEngine{rules: rules, limits: limits, workers: 1}
+	engine.ClearBoard()
+	return engine
+}
+
+func (e *ConnectEngine) SetWorkers(workers int) {
+	e.workers = workers
+	e.tree.SetWorkers(workers)
+}
+
+// BoardSize rejects boards too small for a winning row.
+func (e *ConnectEngine) BoardSize(size int) error {
+	if size < e.rules.Row || size > board.MaxSize {
+		return errors.New("unacceptable size")
+	}
+	e.rules.Width, e.rules.Height = size, size
+	e.ClearBoard()
+	return nil
+}
+
+func (e *ConnectEngine) ClearBoard() {
+	e.board = e.rules.MakeBoard()
+	e.game = connect.MakeGame(e.rules, maxPlaces)
+	e.newTree()
+}
+
+func (e *ConnectEngine) newTree() {
+	e.tree = tree.NewTree[*connect.Connect, *board.Board, connect.Move](maxMoves)
+	e.tree.SetWorkers(e.workers)
+	e.tree.SetUndos(maxUndos)
+}
+
+func (e *ConnectEngine) Play(turn Turn, moveStr string) error {
+	if turn != e.game.Turn() {
+		return errors.New("wrong color")
+	}
+	move, err := connect.ParseMove(moveStr)
+	if err != nil {
+		return err
+	}
+	return e.play(move)
+}
+
+func (e *ConnectEngine) play(move connect.Move) error {
+	if _, err := e.game.PlayMove(&e.board, move); err != nil {
+		return err
+	}
+	e.tree.CommitMove(move)
+	return nil
+}
+
+func (e *ConnectEngine) GenMove(turn Turn) (string, error) {
+	if turn != e.game.Turn() {
+		return "", errors.New("wrong color")
+	}
+	if e.board.IsOver() {
+		return "", connect.ErrGameOver
+	}
+	limits := e.limits
+	if duration := e.moveTime(); duration > 0 {
+		limits.Duration = duration
+	}
+	decision, stats, ok := e.tree.Search(context.Background(), &e.board, &e.game, limits)
+	e.mainTime = max(0, e.mainTime-stats.Duration)
+	if !ok {
+		return "", errors.New("no move found")
+	}
+	if err := e.play(decision.Move); err != nil {
+		return "", err
+	}
+	return decision.Move.String(), nil
+}
+
+// moveTime spends a twentieth of the main time and the byo-yomi time of one
+// move, or returns 0 without time settings.
+func (e *ConnectEngine) moveTime() time.Duration {
+	duration := e.mainTime / 20
+	if e.byoYomiStones > 0 {
+		duration += e.byoYomiTime / time.Duration(e.byoYomiStones)
+	}
+	return duration
+}
+
+func (e *ConnectEngine) Undo() error {
+	if _, err := e.game.UndoMove(&e.board); err != nil {
+		return err
+	}
+	if !e.tree.UndoMove() {
+		e.newTree()
+	}
+	return nil
+}
+
+func (e *ConnectEngine) ShowBoard() string {
+	return e.board.String()
+}
+
+func (e *ConnectEngine) TimeSettings(mainTime, byoYomiTime time.Duration, byoYomiStones int) {
+	e.mainTime, e.byoYomiTime, e.byoYomiStones = mainTime, byoYomiTime, byoYomiStones
+}
+
+func (e *ConnectEngine) FinalStatus() string {
+	switch {
+	case e.board.Winner() == board.Black:
+		return "B+"
+	case e.board.Winner() == board.White:
+		return "W+"
+	case e.board.IsDraw():
+		return "0"
+	}
+	return "unfinished"
+}
@@ -0,0 +1,196 @@
+// Package gtp implements a line based engine protocol modeled on the Go Text
+// Protocol. The protocol layer only knows moves as strings; the game is
+// behind the Engine interface, see ConnectEngine for the connect games.
+//
+// Every command is a line "[id] name [arguments]". A successful response is
+// "=[id] result" and a failed one "?[id] message", each followed by an empty
+// line.
+package gtp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	. "monte/common"
+)
+
+// Engine plays the game for the protocol. Moves are in the notation of the
+// game.
+type Engine interface {
+	BoardSize(size int) error
+	ClearBoard()
+	Play(turn Turn, move string) error
+	GenMove(turn Turn) (string, error)
+	Undo() error
+	ShowBoard() string
+	TimeSettings(mainTime, byoYomiTime time.Duration, byoYomiStones int)
+	// FinalStatus returns "B+" or "W+" for a win, "0" for a draw and
+	// "unfinished" before the game is over.
+	FinalStatus() string
+}
+
+type Server struct {
+	engine  Engine
+	name    string
+	version string
+}
+
+func NewServer(engine Engine, name, version string) *Server {
+	return &Server{engine: engine, name: name, version: version}
+}
+
+var commands = []string{
+	"boardsize",
+	"clear_board",
+	"final_status",
+	"genmove",
+	"known_command",
+	"list_commands",
+	"name",
+	"play",
+	"protocol_version",
+	"quit",
+	"showboard",
+	"time_settings",
+	"undo",
+	"version",
+}
+
+var errQuit = errors.New("quit")
+
+// Run answers the commands read from in until the input ends or a quit
+// command.
+func (s *Server) Run(in io.Reader, out io.Writer) error {
+	input := bufio.NewScanner(in)
+	for input.Scan() {
+		id, name, args, ok := parseLine(input.Text())
+		if !ok {
+			continue
+		}
+		result, err := s.execute(name, args)
+		if err != nil && err != errQuit {
+			fmt.Fprintf(out, "?%s %v\n\n", id, err)
+			continue
+		}
+		fmt.Fprintf(out, "=%s %s\n\n", id, result)
+		if err == errQuit {
+			return nil
+		}
+	}
+	return input.Err()
+}
+
+// parseLine splits a command line into its id, if any, the command name and
+// its arguments. Comments and empty lines are not commands.
+func parseLine(line string) (string, string, []string, bool) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) == 0 {
+		return "", "", nil, false
+	}
+	id := ""
+	if _, err := strconv.Atoi(fields[0]); err == nil {
+		id = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return id, "", nil, true
+	}
+	return id, fields[0], fields[1:], true
+}
+
+func (s *Server) execute(name string, args []string) (string, error) {
+	switch name {
+	case "protocol_version":
+		return "2", nil
+	case "name":
+		return s.name, nil
+	case "version":
+		return s.version, nil
+	case "known_command":
+		if len(args) != 1 {
+			return "", errors.New("syntax error")
+		}
+		for _, command := range commands {
+			if command == args[0] {
+				return "true", nil
+			}
+		}
+		return "false", nil
+	case "list_commands":
+		return strings.Join(commands, "\n"), nil
+	case "quit":
+		return "", errQuit
+	case "boardsize":
+		if len(args) != 1 {
+			return "", errors.New("syntax error")
+		}
+		size, err := strconv.Atoi(args[0])
+		if err != nil {
+			return "", errors.New("syntax error")
+		}
+		if err := s.engine.BoardSize(size); err != nil {
+			return "", err
+		}
+		return "", nil
+	case "clear_board":
+		s.engine.ClearBoard()
+		return "", nil
+	case "play":
+		if len(args) != 2 {
+			return "", errors.New("syntax error")
+		}
+		turn, err := parseColor(args[0])
+		if err != nil {
+			return "", err
+		}
+		return "", s.engine.Play(turn, args[1])
+	case "genmove":
+		if len(args) != 1 {
+			return "", errors.New("syntax error")
+		}
+		turn, err := parseColor(args[0])
+		if err != nil {
+			return "", err
+		}
+		return s.engine.GenMove(turn)
+	case "undo":
+		return "", s.engine.Undo()
+	case "showboard":
+		return strings.TrimRight(s.engine.ShowBoard(), "\n"), nil
+	case "time_settings":
+		if len(args) != 3 {
+			return "", errors.New("syntax error")
+		}
+		values := [3]int{}
+		for i, arg := range args {
+			value, err := strconv.Atoi(arg)
+			if err != nil || value < 0 {
+				return "", errors.New("syntax error")
+			}
+			values[i] = value
+		}
+		s.engine.TimeSettings(time.Duration(values[0])*time.Second, time.Duration(values[1])*time.Second, values[2])
+		return "", nil
+	case "final_status":
+		return s.engine.FinalStatus(), nil
+	}
+	return "", errors.New("unknown command")
+}
+
+func parseColor(color string) (Turn, error) {
+	switch color {
+	case "b", "black":
+		return First, nil
+	case "w", "white":
+		return Second, nil
+	}
+	return First, errors.New("invalid color")
+}
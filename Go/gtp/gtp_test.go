@@ -0,0 +1,121 @@
+package gtp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"monte/connect"
+	"monte/connect6"
+	"monte/tree"
+)
+
+// session runs the commands and returns the responses without the empty
+// lines that end them.
+func session(t *testing.T, engine Engine, commands ...string) []string {
+	out := &bytes.Buffer{}
+	server := NewServer(engine, "monte", "test")
+	if err := server.Run(strings.NewReader(strings.Join(commands, "\n")), out); err != nil {
+		t.Fatal(err)
+	}
+	responses := strings.Split(strings.TrimSuffix(out.String(), "\n\n"), "\n\n")
+	return responses
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line, id, name string
+		args           []string
+		ok             bool
+	}{
+		{"", "", "", nil, false},
+		{"  # comment", "", "", nil, false},
+		{"name", "", "name", nil, true},
+		{"12 play B j10 # first", "12", "play", []string{"b", "j10"}, true},
+		{"3\tgenmove\twhite", "3", "genmove", []string{"white"}, true},
+	}
+	for _, test := range tests {
+		id, name, args, ok := parseLine(test.line)
+		if id != test.id || name != test.name || fmt.Sprint(args) != fmt.Sprint(test.args) || ok != test.ok {
+			fmt.Printf("%q: %q %q %q %v\n", test.line, id, name, args, ok)
+			t.Fail()
+		}
+	}
+}
+
+func TestConnect6Session(t *testing.T) {
+	engine := NewConnectEngine(connect6.Rules, tree.Limits{Simulations: 10})
+	responses := session(t, engine,
+		"1 protocol_version",
+		"2 known_command genmove",
+		"3 known_command fly",
+		"4 boardsize 20",
+		"5 boardsize 19",
+		"6 play b j10",
+		"7 play b k10-k11",
+		"8 play w j10-k11",
+		"9 genmove w",
+		"10 undo",
+		"11 play w k10-k11",
+		"12 genmove b",
+		"13 final_status",
+		"14 showboard",
+		"15 fly",
+		"16 quit",
+		"17 name",
+	)
+	expected := []string{
+		"=1 2",
+		"=2 true",
+		"=3 false",
+		"?4 unacceptable size",
+		"=5 ",
+		"=6 ",
+		"?7 wrong color",
+		"?8 move j10-k11: place occupied",
+		"=9 ",
+		"=10 ",
+		"=11 ",
+		"=12 ",
+		"=13 unfinished",
+		"=14 ",
+		"?15 unknown command",
+		"=16 ",
+	}
+	if len(responses) != len(expected) {
+		fmt.Println(strings.Join(responses, "\n--\n"))
+		t.Fatalf("expected %d responses got %d", len(expected), len(responses))
+	}
+	for i, response := range responses {
+		if !strings.HasPrefix(response, expected[i]) {
+			fmt.Printf("expected %q got %q\n", expected[i], response)
+			t.Fail()
+		}
+	}
+	if moves := engine.game.History(); len(moves) != 3 || moves[1].String() != "k11-k10" {
+		fmt.Println(moves)
+		t.Fail()
+	}
+	if !strings.Contains(responses[13], "X─O") {
+		fmt.Println(responses[13])
+		t.Fail()
+	}
+	if engine.BoardSize(connect6.Rules.Row-1) == nil || engine.BoardSize(connect6.Rules.Row) != nil {
+		t.Fail()
+	}
+}
+
+func TestGomokuSession(t *testing.T) {
+	engine := NewConnectEngine(connect.NewRules(15, 15, 5, 1, 1), tree.Limits{Simulations: 50})
+	responses := session(t, engine,
+		"play b h8", "play w a1", "play b h9", "play w a2", "play b h10", "play w a3", "play b h11", "play w a15",
+		"genmove b", "final_status", "genmove w", "time_settings 10 0 0")
+	expected := []string{"=", "=", "=", "=", "=", "=", "=", "=", "= h", "= B+", "? game over", "="}
+	for i, response := range responses {
+		if !strings.HasPrefix(response, expected[i]) {
+			fmt.Printf("expected %q got %q\n", expected[i], response)
+			t.Fail()
+		}
+	}
+}
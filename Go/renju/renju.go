@@ -0,0 +1,47 @@
+// Package renju holds the Renju preset of the connect package: Gomoku on a
+// 15x15 board where the first player, Black, may not play double-threes,
+// double-fours or overlines.
+package renju
+
+import (
+	"monte/board"
+	"monte/connect"
+)
+
+type (
+	Renju = connect.Connect
+	Move  = connect.Move
+)
+
+// Black can only win with exactly five stones as longer rows are forbidden,
+// while White wins with five or more.
+var Rules = connect.Rules{
+	Width:       15,
+	Height:      15,
+	Row:         5,
+	Stones:      1,
+	FirstStones: 1,
+	Scoring:     board.RenjuScoring,
+	Renju:       true,
+}
+
+func MakeBoard() board.Board {
+	return Rules.MakeBoard()
+}
+
+func MakeGame(maxPlaces int) Renju {
+	return connect.MakeGame(Rules, maxPlaces)
+}
+
+func ExpFactor() float64 {
+	return 1
+}
+
+func ParseMove(moveStr string) (Move, error) {
+	return connect.ParseMove(moveStr)
+}
+
+// ParsePosition parses a position written by Renju.Position.
+func ParsePosition(position string, maxPlaces int) (board.Board, Renju, error) {
+	return connect.ParsePosition(Rules, position, maxPlaces)
+}
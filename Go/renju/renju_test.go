@@ -0,0 +1,80 @@
+package renju
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"monte/board"
+	"monte/common"
+	"monte/connect"
+)
+
+// doubleThree leaves Black to move with h8 a double-three.
+func doubleThree(t *testing.T) (board.Board, Renju) {
+	b := MakeBoard()
+	game := MakeGame(40)
+	if _, err := game.PlayMoves(&b, "f8", "a1", "g8", "a3", "h6", "a5", "h7", "a7"); err != nil {
+		t.Fatal(err)
+	}
+	return b, game
+}
+
+func TestForbiddenMove(t *testing.T) {
+	b, game := doubleThree(t)
+	before, turn := b, game.Turn()
+	move, _ := ParseMove("h8")
+	if _, err := game.PlayMove(&b, move); !errors.Is(err, connect.ErrForbidden) {
+		fmt.Println("expected a forbidden move got", err)
+		t.Fail()
+	}
+	if b != before || game.Turn() != turn {
+		t.Fail()
+	}
+
+	if _, err := game.PlayMoves(&b, "o15", "h8"); err != nil {
+		t.Fatal(err)
+	}
+	if b.Stone(7, 7) != board.White {
+		t.Fail()
+	}
+}
+
+func TestTopMoves(t *testing.T) {
+	b, game := doubleThree(t)
+	forbidden, _ := ParseMove("h8")
+	moves := make([]common.MoveValue[Move], 0, 40)
+	game.TopMoves(&b, &moves)
+	for _, move := range moves {
+		if move.Move == forbidden {
+			fmt.Println(&b, moves)
+			t.Fail()
+		}
+	}
+
+	if _, err := game.PlayMoves(&b, "o15"); err != nil {
+		t.Fatal(err)
+	}
+	game.TopMoves(&b, &moves)
+	found := false
+	for _, move := range moves {
+		found = found || move.Move == forbidden
+	}
+	if !found {
+		fmt.Println("White may play", forbidden)
+		t.Fail()
+	}
+}
+
+func TestWhiteOverline(t *testing.T) {
+	b := MakeBoard()
+	game := MakeGame(10)
+	if _, err := game.PlayMoves(&b, "o1", "b8", "o3", "c8", "o5", "d8", "o7", "f8", "o9", "g8", "o11"); err != nil {
+		t.Fatal(err)
+	}
+	move, _ := ParseMove("e8")
+	if result, err := game.PlayMove(&b, move); result != common.Won || err != nil {
+		fmt.Println(result, err)
+		t.Fail()
+	}
+}
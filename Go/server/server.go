@@ -0,0 +1,513 @@
+// Package server serves connect games and their analysis as JSON over HTTP.
+// Every game is a session that keeps its search tree between moves:
+//
+//	POST   /sessions              create a session: {"game": "connect6", "position": "..."}
+//	GET    /sessions/{id}         get the session
+//	DELETE /sessions/{id}         end the session
+//	POST   /sessions/{id}/moves   play a move: {"move": "i9-i11"}
+//	POST   /sessions/{id}/undo    take back the last move
+//	POST   /sessions/{id}/search  start a search: {"duration": "2s", "simulations": 1000}
+//	DELETE /sessions/{id}/search  stop the search
+//
+// The position is optional and in the notation of connect.ParsePosition. A
+// search runs in the background until one of its limits is reached or it is
+// stopped; playing or taking back a move stops it too. Every request returns
+// the session as a Session, failed ones an Error. Sessions without requests
+// for longer than the idle timeout are ended, and no more sessions are created
+// once the maximum number of sessions is reached.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"monte/board"
+	. "monte/common"
+	"monte/connect"
+	"monte/tree"
+)
+
+// MaxDuration bounds the duration of a search.
+const MaxDuration = time.Minute
+
+// Session limits of a new Server, see SetSessionLimits.
+const (
+	DefaultMaxSessions = 100
+	DefaultIdleTimeout = 30 * time.Minute
+)
+
+const (
+	maxMoves      = 60
+	maxPlaces     = 32
+	maxCandidates = 10
+	maxUndos      = 1
+)
+
+// Session is the JSON form of a game. Board has a row per string, from row 1,
+// with "x" for black, "o" for white and "." for empty places. Result is "B+"
+// or "W+" for a win, "0" for a draw and empty while the game goes on.
+type Session struct {
+	ID       string    `json:"id"`
+	Game     string    `json:"game"`
+	Position string    `json:"position"`
+	Board    []string  `json:"board"`
+	Turn     string    `json:"turn"`
+	Stones   int       `json:"stones"`
+	Moves    []string  `json:"moves"`
+	Result   string    `json:"result,omitempty"`
+	Search   *Analysis `json:"search,omitempty"`
+}
+
+// Analysis is the state of the last search of a session. The values are from
+// the point of view of the player to move, between -1 and 1; Proven is "win",
+// "loss" or "draw" for a proven move, with a value of 1, -1 or 0. The search
+// prefers the most simulated moves, and the candidates are the best ten of
+// them.
+type Analysis struct {
+	Running     bool        `json:"running"`
+	BestMove    string      `json:"bestMove,omitempty"`
+	Value       float64     `json:"value"`
+	Proven      string      `json:"proven,omitempty"`
+	PV          []string    `json:"pv,omitempty"`
+	Candidates  []Candidate `json:"candidates,omitempty"`
+	Simulations int         `json:"simulations"`
+	Millis      int64       `json:"millis"`
+	Reason      string      `json:"reason,omitempty"`
+}
+
+// Candidate is a root move of the search with the line expected after it,
+// which starts with the move.
+type Candidate struct {
+	Move   string   `json:"move"`
+	Value  float64  `json:"value"`
+	Visits int      `json:"visits"`
+	Proven string   `json:"proven,omitempty"`
+	PV     []string `json:"pv"`
+}
+
+// Error is the JSON form of a failed request.
+type Error struct {
+	Error string `json:"error"`
+}
+
+type Server struct {
+	games       map[string]connect.Rules
+	limits      tree.Limits
+	workers     int
+	maxSessions int
+	idleTimeout time.Duration
+	now         func() time.Time
+	mux         *http.ServeMux
+	mutex       sync.Mutex
+	sessions    map[string]*session
+}
+
+// session is a game. used is the time of its last request and guarded by the
+// server mutex.
+type session struct {
+	id     string
+	game   string
+	used   time.Time
+	mutex  sync.Mutex
+	board  board.Board
+	state  connect.Connect
+	tree   *tree.Tree[*connect.Connect, *board.Board, connect.Move]
+	search *search
+}
+
+// search is a background search. The analysis is written before done is
+// closed and only read after that.
+type search struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	analysis Analysis
+}
+
+// NewServer returns a server for the games by name. A search without limits
+// of its own uses limits.
+func NewServer(games map[string]connect.Rules, limits tree.Limits) *Server {
+	s := &Server{
+		games:       games,
+		limits:      limits,
+		workers:     1,
+		maxSessions: DefaultMaxSessions,
+		idleTimeout: DefaultIdleTimeout,
+		now:         time.Now,
+		mux:         http.NewServeMux(),
+		sessions:    map[string]*session{},
+	}
+	s.mux.HandleFunc("POST /sessions", s.create)
+	s.mux.HandleFunc("GET /sessions/{id}", s.withSession(s.get))
+	s.mux.HandleFunc("DELETE /sessions/{id}", s.delete)
+	s.mux.HandleFunc("POST /sessions/{id}/moves", s.withSession(s.play))
+	s.mux.HandleFunc("POST /sessions/{id}/undo", s.withSession(s.undo))
+	s.mux.HandleFunc("POST /sessions/{id}/search", s.withSession(s.startSearch))
+	s.mux.HandleFunc("DELETE /sessions/{id}/search", s.withSession(s.stopSearch))
+	return s
+}
+
+// SetWorkers sets the number of goroutines of every search.
+func (s *Server) SetWorkers(workers int) {
+	s.workers = max(1, workers)
+}
+
+// SetSessionLimits sets the maximum number of sessions and the time after
+// which a session without requests is ended, with 0 for sessions that only
+// end when they are deleted.
+func (s *Server) SetSessionLimits(maxSessions int, idleTimeout time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxSessions = max(1, maxSessions)
+	s.idleTimeout = idleTimeout
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close stops all searches and ends all sessions.
+func (s *Server) Close() {
+	s.mutex.Lock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.sessions = map[string]*session{}
+	s.mutex.Unlock()
+	end(sessions)
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Game     string `json:"game"`
+		Position string `json:"position"`
+	}
+	if !decode(w, r, &request) {
+		return
+	}
+	rules, ok := s.games[request.Game]
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown game %q", request.Game))
+		return
+	}
+	sess := &session{
+		id:    newID(),
+		game:  request.Game,
+		board: rules.MakeBoard(),
+		state: connect.MakeGame(rules, maxPlaces),
+	}
+	if request.Position != "" {
+		var err error
+		if sess.board, sess.state, err = connect.ParsePosition(rules, request.Position, maxPlaces); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	sess.newTree(s.workers)
+
+	s.mutex.Lock()
+	expired := s.expire()
+	full := len(s.sessions) >= s.maxSessions
+	if !full {
+		sess.used = s.now()
+		s.sessions[sess.id] = sess
+	}
+	s.mutex.Unlock()
+	end(expired)
+	if full {
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many sessions"))
+		return
+	}
+	w.Header().Set("Location", "/sessions/"+sess.id)
+	writeJSON(w, http.StatusCreated, sess.json())
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	sess, ok := s.sessions[r.PathValue("id")]
+	delete(s.sessions, r.PathValue("id"))
+	s.mutex.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown session"))
+		return
+	}
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+	sess.stop()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// withSession looks up the session of the request and holds its lock while
+// handle runs.
+func (s *Server) withSession(handle func(http.ResponseWriter, *http.Request, *session)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		expired := s.expire()
+		sess, ok := s.sessions[r.PathValue("id")]
+		if ok {
+			sess.used = s.now()
+		}
+		s.mutex.Unlock()
+		end(expired)
+		if !ok {
+			writeError(w, http.StatusNotFound, errors.New("unknown session"))
+			return
+		}
+		sess.mutex.Lock()
+		defer sess.mutex.Unlock()
+		handle(w, r, sess)
+	}
+}
+
+// expire removes the sessions that were idle for too long and returns them.
+// The server mutex has to be held.
+func (s *Server) expire() []*session {
+	expired := []*session{}
+	for id, sess := range s.sessions {
+		if s.idleTimeout > 0 && s.now().Sub(sess.used) > s.idleTimeout {
+			delete(s.sessions, id)
+			expired = append(expired, sess)
+		}
+	}
+	return expired
+}
+
+// end stops the searches of removed sessions.
+func end(sessions []*session) {
+	for _, sess := range sessions {
+		sess.mutex.Lock()
+		sess.stop()
+		sess.mutex.Unlock()
+	}
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, sess *session) {
+	writeJSON(w, http.StatusOK, sess.json())
+}
+
+func (s *Server) play(w http.ResponseWriter, r *http.Request, sess *session) {
+	var request struct {
+		Move string `json:"move"`
+	}
+	if !decode(w, r, &request) {
+		return
+	}
+	move, err := connect.ParseMove(request.Move)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	sess.stop()
+	if _, err := sess.state.PlayMove(&sess.board, move); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	sess.tree.CommitMove(move)
+	sess.search = nil
+	writeJSON(w, http.StatusOK, sess.json())
+}
+
+func (s *Server) undo(w http.ResponseWriter, r *http.Request, sess *session) {
+	sess.stop()
+	if _, err := sess.state.UndoMove(&sess.board); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if !sess.tree.UndoMove() {
+		sess.newTree(s.workers)
+	}
+	sess.search = nil
+	writeJSON(w, http.StatusOK, sess.json())
+}
+
+func (s *Server) startSearch(w http.ResponseWriter, r *http.Request, sess *session) {
+	var request struct {
+		Duration    string `json:"duration"`
+		Simulations int    `json:"simulations"`
+	}
+	if !decode(w, r, &request) {
+		return
+	}
+	limits := tree.Limits{Simulations: request.Simulations}
+	if request.Duration != "" {
+		duration, err := time.ParseDuration(request.Duration)
+		if err != nil || duration <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid duration %q", request.Duration))
+			return
+		}
+		limits.Duration = duration
+	}
+	if limits.Duration == 0 && limits.Simulations <= 0 {
+		limits = s.limits
+	}
+	if limits.Duration == 0 || limits.Duration > MaxDuration {
+		limits.Duration = MaxDuration
+	}
+	if sess.board.IsOver() {
+		writeError(w, http.StatusConflict, connect.ErrGameOver)
+		return
+	}
+	sess.stop()
+	sess.start(limits)
+	writeJSON(w, http.StatusAccepted, sess.json())
+}
+
+func (s *Server) stopSearch(w http.ResponseWriter, r *http.Request, sess *session) {
+	sess.stop()
+	writeJSON(w, http.StatusOK, sess.json())
+}
+
+func (sess *session) newTree(workers int) {
+	sess.tree = tree.NewTree[*connect.Connect, *board.Board, connect.Move](maxMoves)
+	sess.tree.SetWorkers(workers)
+	sess.tree.SetFinalMove(tree.MaxVisits)
+	sess.tree.SetUndos(maxUndos)
+}
+
+// start runs a search on copies of the board and the game. The tree is left
+// to the search until stop returns.
+func (sess *session) start(limits tree.Limits) {
+	ctx, cancel := context.WithCancel(context.Background())
+	search := &search{cancel: cancel, done: make(chan struct{})}
+	sess.search = search
+	tr, b, game := sess.tree, sess.board, sess.state
+	go func() {
+		defer close(search.done)
+		decision, stats, ok := tr.Search(ctx, &b, &game, limits)
+		search.analysis = analysis(tr, decision, stats, ok)
+	}()
+}
+
+// stop stops the running search and waits for it.
+func (sess *session) stop() {
+	if sess.search != nil {
+		sess.search.cancel()
+		<-sess.search.done
+	}
+}
+
+func analysis(tr *tree.Tree[*connect.Connect, *board.Board, connect.Move], decision tree.Decision[connect.Move], stats tree.Stats, ok bool) Analysis {
+	result := Analysis{
+		Simulations: stats.Simulations,
+		Millis:      stats.Duration.Milliseconds(),
+		Reason:      stats.Reason.String(),
+		PV:          moveStrings(tr.PrincipalVariation(tree.MaxVisits)),
+		Candidates:  []Candidate{},
+	}
+	if ok {
+		result.BestMove = decision.Move.String()
+		result.Value, result.Proven = value(decision.Value)
+	}
+	for _, line := range tr.Lines(maxCandidates, tree.MaxVisits) {
+		candidate := Candidate{Move: line.Move.String(), Visits: line.NSims, PV: moveStrings(line.PV)}
+		candidate.Value, candidate.Proven = value(line.Value)
+		result.Candidates = append(result.Candidates, candidate)
+	}
+	return result
+}
+
+func moveStrings(moves []connect.Move) []string {
+	result := make([]string, len(moves))
+	for i, move := range moves {
+		result[i] = move.String()
+	}
+	return result
+}
+
+// value splits a value into a number, which JSON has no infinities for, and
+// its proven status.
+func value(v Value) (float64, string) {
+	switch {
+	case v.IsWin():
+		return 1, "win"
+	case v.IsLoss():
+		return -1, "loss"
+	case v.IsDraw():
+		return 0, "draw"
+	}
+	return float64(v), ""
+}
+
+func (sess *session) json() Session {
+	result := Session{
+		ID:       sess.id,
+		Game:     sess.game,
+		Position: sess.state.Position(&sess.board),
+		Board:    make([]string, sess.board.Height()),
+		Turn:     "black",
+		Stones:   sess.state.Stones(),
+		Moves:    moveStrings(sess.state.History()),
+	}
+	for y := range result.Board {
+		row := strings.Builder{}
+		for x := range sess.board.Width() {
+			switch sess.board.Stone(int8(x), int8(y)) {
+			case board.Black:
+				row.WriteByte('x')
+			case board.White:
+				row.WriteByte('o')
+			default:
+				row.WriteByte('.')
+			}
+		}
+		result.Board[y] = row.String()
+	}
+	if sess.state.Turn() == Second {
+		result.Turn = "white"
+	}
+	switch {
+	case sess.board.Winner() == board.Black:
+		result.Result = "B+"
+	case sess.board.Winner() == board.White:
+		result.Result = "W+"
+	case sess.board.IsDraw():
+		result.Result = "0"
+	}
+	if sess.search != nil {
+		select {
+		case <-sess.search.done:
+			analysis := sess.search.analysis
+			result.Search = &analysis
+		default:
+			result.Search = &Analysis{Running: true}
+		}
+	}
+	return result
+}
+
+func newID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// decode reads the JSON body of the request into v, or writes an error and
+// returns false. An empty body leaves v alone.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Error{Error: err.Error()})
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"monte/connect"
+	"monte/connect6"
+	"monte/gomoku"
+	"monte/tree"
+)
+
+func newTestServer() *Server {
+	games := map[string]connect.Rules{"connect6": connect6.Rules, "gomoku": gomoku.Freestyle}
+	return NewServer(games, tree.Limits{Simulations: 20})
+}
+
+// do sends a request and decodes the response into a Session, or an Error for
+// a failed request.
+func do(t *testing.T, s *Server, method, path, body string) (int, Session, Error) {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	session, failure := Session{}, Error{}
+	if w.Code == http.StatusNoContent {
+		return w.Code, session, failure
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Fatal(method, path, "content type", w.Header().Get("Content-Type"))
+	}
+	var err error
+	if w.Code >= 400 {
+		err = json.Unmarshal(w.Body.Bytes(), &failure)
+	} else {
+		err = json.Unmarshal(w.Body.Bytes(), &session)
+	}
+	if err != nil {
+		t.Fatal(method, path, err, w.Body.String())
+	}
+	return w.Code, session, failure
+}
+
+// wait polls the session until its search is done.
+func wait(t *testing.T, s *Server, id string) Session {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		_, session, _ := do(t, s, "GET", "/sessions/"+id, "")
+		if session.Search == nil || !session.Search.Running {
+			return session
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("search did not stop")
+	return Session{}
+}
+
+func TestSession(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	code, session, _ := do(t, s, "POST", "/sessions", `{"game": "connect6"}`)
+	if code != http.StatusCreated || session.ID == "" || session.Turn != "black" || session.Stones != 1 || len(session.Board) != 19 {
+		fmt.Println(code, session)
+		t.Fatal()
+	}
+	path := "/sessions/" + session.ID
+
+	code, session, _ = do(t, s, "POST", path+"/moves", `{"move": "j10"}`)
+	if code != http.StatusOK || session.Turn != "white" || session.Stones != 2 || session.Board[9][9] != 'x' ||
+		len(session.Moves) != 1 || session.Moves[0] != "j10" {
+		fmt.Println(code, session)
+		t.Fail()
+	}
+	tests := []struct {
+		body string
+		code int
+	}{
+		{`{"move": "j10-k10"}`, http.StatusConflict},
+		{`{"move": "k10"}`, http.StatusConflict},
+		{`{"move": "z99"}`, http.StatusBadRequest},
+		{`{"place": "k10"}`, http.StatusBadRequest},
+		{`{"move": `, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if code, _, failure := do(t, s, "POST", path+"/moves", test.body); code != test.code || failure.Error == "" {
+			fmt.Println(test.body, code, failure)
+			t.Fail()
+		}
+	}
+
+	code, session, _ = do(t, s, "POST", path+"/undo", "")
+	if code != http.StatusOK || session.Turn != "black" || len(session.Moves) != 0 || session.Board[9][9] != '.' {
+		fmt.Println(code, session)
+		t.Fail()
+	}
+	if code, _, _ := do(t, s, "POST", path+"/undo", ""); code != http.StatusConflict {
+		t.Fail()
+	}
+
+	if code, _, _ := do(t, s, "DELETE", path, ""); code != http.StatusNoContent {
+		t.Fail()
+	}
+	if code, _, _ := do(t, s, "GET", path, ""); code != http.StatusNotFound {
+		t.Fail()
+	}
+	for _, body := range []string{`{"game": "chess"}`, `{"game": "connect6", "position": "19/19 w 2"}`} {
+		if code, _, _ := do(t, s, "POST", "/sessions", body); code != http.StatusBadRequest {
+			fmt.Println(body, code)
+			t.Fail()
+		}
+	}
+}
+
+func TestSessionLimits(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	s.SetSessionLimits(2, time.Minute)
+	ids := []string{}
+	for range 2 {
+		_, session, _ := do(t, s, "POST", "/sessions", `{"game": "connect6"}`)
+		ids = append(ids, session.ID)
+	}
+	if code, _, _ := do(t, s, "POST", "/sessions", `{"game": "connect6"}`); code != http.StatusServiceUnavailable {
+		fmt.Println(code)
+		t.Fail()
+	}
+
+	now = now.Add(50 * time.Second)
+	do(t, s, "POST", "/sessions/"+ids[1]+"/search", `{"duration": "30s"}`)
+	now = now.Add(50 * time.Second)
+	if code, _, _ := do(t, s, "POST", "/sessions", `{"game": "connect6"}`); code != http.StatusCreated {
+		fmt.Println(code)
+		t.Fail()
+	}
+	if code, _, _ := do(t, s, "GET", "/sessions/"+ids[0], ""); code != http.StatusNotFound {
+		fmt.Println(code)
+		t.Fail()
+	}
+	if code, _, _ := do(t, s, "GET", "/sessions/"+ids[1], ""); code != http.StatusOK {
+		fmt.Println(code)
+		t.Fail()
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	_, session, _ := do(t, s, "POST", "/sessions", `{"game": "connect6"}`)
+	path := "/sessions/" + session.ID
+	do(t, s, "POST", path+"/moves", `{"move": "j10"}`)
+
+	code, session, _ := do(t, s, "POST", path+"/search", `{"simulations": 40}`)
+	if code != http.StatusAccepted || session.Search == nil {
+		fmt.Println(code, session)
+		t.Fatal()
+	}
+	session = wait(t, s, session.ID)
+	analysis := session.Search
+	if analysis == nil || analysis.BestMove == "" || len(analysis.PV) == 0 || analysis.PV[0] != analysis.BestMove ||
+		len(analysis.Candidates) == 0 || analysis.Simulations == 0 || analysis.Reason == "" {
+		fmt.Println(session, analysis)
+		t.Fatal()
+	}
+	if len(analysis.Candidates) > 10 || analysis.Candidates[0].Move != analysis.BestMove {
+		fmt.Println(analysis.Candidates)
+		t.Fail()
+	}
+	for i, candidate := range analysis.Candidates {
+		if i > 0 && candidate.Visits > analysis.Candidates[i-1].Visits || len(candidate.PV) == 0 || candidate.PV[0] != candidate.Move {
+			fmt.Println(analysis.Candidates)
+			t.Fail()
+		}
+	}
+	if code, session, _ := do(t, s, "POST", path+"/moves", fmt.Sprintf(`{"move": %q}`, analysis.BestMove)); code != http.StatusOK || session.Search != nil {
+		fmt.Println(code, session)
+		t.Fail()
+	}
+
+	do(t, s, "POST", path+"/search", `{"duration": "30s"}`)
+	code, session, _ = do(t, s, "DELETE", path+"/search", "")
+	if code != http.StatusOK || session.Search == nil || session.Search.Running || session.Search.Reason != "canceled" {
+		fmt.Println(code, session.Search)
+		t.Fail()
+	}
+	if code, _, _ := do(t, s, "POST", path+"/search", `{"duration": "soon"}`); code != http.StatusBadRequest {
+		t.Fail()
+	}
+}
+
+func TestProvenWin(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	position := "15/15/15/15/15/15/15/3xxxx8/15/15/15/o1o1o1o8/15/15/15 b 1"
+	code, session, _ := do(t, s, "POST", "/sessions", fmt.Sprintf(`{"game": "gomoku", "position": %q}`, position))
+	if code != http.StatusCreated || session.Position != position {
+		fmt.Println(code, session)
+		t.Fatal()
+	}
+	do(t, s, "POST", "/sessions/"+session.ID+"/search", "")
+	session = wait(t, s, session.ID)
+	if analysis := session.Search; analysis.Proven != "win" || analysis.Value != 1 ||
+		(analysis.BestMove != "c8" && analysis.BestMove != "h8") {
+		fmt.Println(analysis)
+		t.Fail()
+	}
+
+	do(t, s, "POST", "/sessions/"+session.ID+"/moves", `{"move": "h8"}`)
+	code, session, _ = do(t, s, "GET", "/sessions/"+session.ID, "")
+	if code != http.StatusOK || session.Result != "B+" {
+		fmt.Println(code, session)
+		t.Fail()
+	}
+	if code, _, _ := do(t, s, "POST", "/sessions/"+session.ID+"/search", ""); code != http.StatusConflict {
+		t.Fail()
+	}
+}
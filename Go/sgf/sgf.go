@@ -0,0 +1,282 @@
+// Package sgf reads and writes Connect6 game records in the Smart Game
+// Format. Moves are property values in the notation of connect6.ParseMove,
+// for example ";B[j10];W[i9-i11]", and only the main line of a record with
+// variations is read. Comments of nodes without a move are joined to the
+// comment of the position they follow. Property identifiers with lowercase
+// letters, as written by FF[3] and older programs, are read without them, so
+// "GaMe" is "GM".
+package sgf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"monte/board"
+	"monte/connect"
+	"monte/connect6"
+)
+
+// GameConnect6 is the GM value written to Connect6 records. The SGF
+// specification does not assign one to Connect6, so this is the value of this
+// package; other programs often leave GM out. Parse accepts records with this
+// value or without GM.
+const GameConnect6 = 511
+
+// Record is a game with its board size, players, result and comments. Result
+// follows the SGF conventions: "B+", "W+", "0" for a draw or "" when unknown.
+type Record struct {
+	Size    int
+	Black   string
+	White   string
+	Result  string
+	Comment string
+	Moves   []Move
+}
+
+type Move struct {
+	Move    connect6.Move
+	Comment string
+}
+
+// Result returns the SGF result of the game on the board.
+func Result(b *board.Board) string {
+	switch {
+	case b.Winner() == board.Black:
+		return "B+"
+	case b.Winner() == board.White:
+		return "W+"
+	case b.IsDraw():
+		return "0"
+	}
+	return ""
+}
+
+func (r *Record) String() string {
+	buf := &bytes.Buffer{}
+	r.WriteTo(buf)
+	return buf.String()
+}
+
+func (r *Record) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "(;FF[4]GM[%d]SZ[%d]", GameConnect6, r.Size)
+	writeProperty(buf, "PB", r.Black)
+	writeProperty(buf, "PW", r.White)
+	writeProperty(buf, "RE", r.Result)
+	writeProperty(buf, "C", r.Comment)
+	for i, move := range r.Moves {
+		buf.WriteString("\n;")
+		color := "B"
+		if i%2 == 1 {
+			color = "W"
+		}
+		fmt.Fprintf(buf, "%s[%v]", color, move.Move)
+		writeProperty(buf, "C", move.Comment)
+	}
+	buf.WriteString(")\n")
+	return buf.WriteTo(w)
+}
+
+func writeProperty(buf *bytes.Buffer, name, value string) {
+	if value == "" {
+		return
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "]", `\]`)
+	fmt.Fprintf(buf, "%s[%s]", name, value)
+}
+
+// Play replays the record and returns the final position, with a game that
+// combines maxPlaces places into its moves like connect6.MakeGame.
+func (r *Record) Play(maxPlaces int) (board.Board, connect6.Connect6, error) {
+	rules := connect6.Rules
+	rules.Width, rules.Height = r.Size, r.Size
+	b := rules.MakeBoard()
+	game := connect.MakeGame(rules, maxPlaces)
+	for i, move := range r.Moves {
+		if _, err := game.PlayMove(&b, move.Move); err != nil {
+			return b, game, fmt.Errorf("move %d: %w", i+1, err)
+		}
+	}
+	return b, game, nil
+}
+
+// Read reads a record written by WriteTo or by another program.
+func Read(r io.Reader) (*Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data))
+}
+
+func Parse(text string) (*Record, error) {
+	p := &parser{text: text}
+	nodes, err := p.gameTree()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{Size: 19}
+	root := nodes[0]
+	if game := root.value("GM"); game != "" && game != strconv.Itoa(GameConnect6) {
+		return nil, fmt.Errorf("sgf: not a Connect6 record: GM[%s]", game)
+	}
+	if size := root.value("SZ"); size != "" {
+		record.Size, err = strconv.Atoi(size)
+		if err != nil || record.Size < 1 || record.Size > board.MaxSize {
+			return nil, fmt.Errorf("sgf: unsupported size SZ[%s]", size)
+		}
+	}
+	record.Black = root.value("PB")
+	record.White = root.value("PW")
+	record.Result = root.value("RE")
+	record.Comment = root.value("C")
+
+	for _, node := range nodes[1:] {
+		color, moveStr := "B", node.value("B")
+		if moveStr == "" {
+			color, moveStr = "W", node.value("W")
+		}
+		if moveStr == "" {
+			comment := &record.Comment
+			if len(record.Moves) > 0 {
+				comment = &record.Moves[len(record.Moves)-1].Comment
+			}
+			*comment = joinComments(*comment, node.value("C"))
+			continue
+		}
+		if expected := "BW"[len(record.Moves)%2 : len(record.Moves)%2+1]; color != expected {
+			return nil, fmt.Errorf("sgf: move %d: expected %s to play", len(record.Moves)+1, expected)
+		}
+		move, err := connect6.ParseMove(moveStr)
+		if err != nil {
+			return nil, fmt.Errorf("sgf: move %d: %w", len(record.Moves)+1, err)
+		}
+		record.Moves = append(record.Moves, Move{Move: move, Comment: node.value("C")})
+	}
+	return record, nil
+}
+
+func joinComments(comment, more string) string {
+	if comment == "" || more == "" {
+		return comment + more
+	}
+	return comment + "\n" + more
+}
+
+type node map[string][]string
+
+func (n node) value(name string) string {
+	if values := n[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+var errSyntax = errors.New("sgf: syntax error")
+
+type parser struct {
+	text string
+	pos  int
+}
+
+// peek skips white space and returns the next character, or 0 at the end.
+func (p *parser) peek() byte {
+	for p.pos < len(p.text) && strings.IndexByte(" \t\r\n", p.text[p.pos]) >= 0 {
+		p.pos++
+	}
+	if p.pos == len(p.text) {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+// gameTree parses "(" node... gameTree... ")" and returns the nodes of its
+// main line.
+func (p *parser) gameTree() ([]node, error) {
+	if p.peek() != '(' {
+		return nil, errSyntax
+	}
+	p.pos++
+	nodes := []node{}
+	for p.peek() == ';' {
+		p.pos++
+		n, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	if len(nodes) == 0 {
+		return nil, errSyntax
+	}
+	for first := true; p.peek() == '('; first = false {
+		variation, err := p.gameTree()
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			nodes = append(nodes, variation...)
+		}
+	}
+	if p.peek() != ')' {
+		return nil, errSyntax
+	}
+	p.pos++
+	return nodes, nil
+}
+
+func (p *parser) node() (node, error) {
+	n := node{}
+	for {
+		if c := p.peek(); !isLetter(c) {
+			return n, nil
+		}
+		name := ""
+		for ; p.pos < len(p.text) && isLetter(p.text[p.pos]); p.pos++ {
+			if c := p.text[p.pos]; c >= 'A' && c <= 'Z' {
+				name += string(c)
+			}
+		}
+		if name == "" || p.peek() != '[' {
+			return nil, errSyntax
+		}
+		for p.peek() == '[' {
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			n[name] = append(n[name], value)
+		}
+	}
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+func (p *parser) value() (string, error) {
+	p.pos++
+	value := strings.Builder{}
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		p.pos++
+		switch c {
+		case ']':
+			return value.String(), nil
+		case '\\':
+			if p.pos == len(p.text) {
+				return "", errSyntax
+			}
+			c = p.text[p.pos]
+			p.pos++
+		}
+		value.WriteByte(c)
+	}
+	return "", errSyntax
+}
@@ -0,0 +1,133 @@
+package sgf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"monte/board"
+	"monte/common"
+	"monte/connect"
+	"monte/connect6"
+	"monte/tree"
+)
+
+func parseMove(t *testing.T, moveStr string) connect6.Move {
+	move, err := connect6.ParseMove(moveStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return move
+}
+
+func TestRoundTrip(t *testing.T) {
+	record := &Record{
+		Size:    19,
+		Black:   "monte",
+		White:   "A. [Human]",
+		Result:  "W+",
+		Comment: `a \ test`,
+	}
+	for i, moveStr := range []string{"j10", "i9-i11", "k11-l12", "h8-h12"} {
+		record.Moves = append(record.Moves, Move{Move: parseMove(t, moveStr), Comment: strings.Repeat("!", i%2)})
+	}
+	text := record.String()
+	expected := `(;FF[4]GM[511]SZ[19]PB[monte]PW[A. [Human\]]RE[W+]C[a \\ test]
+;B[j10]
+;W[i11-i9]C[!]
+;B[k11-l12]
+;W[h12-h8]C[!])
+`
+	if text != expected {
+		fmt.Println(text)
+		t.Fail()
+	}
+	parsed, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.String() != text {
+		fmt.Println(parsed)
+		t.Fail()
+	}
+}
+
+func TestParse(t *testing.T) {
+	text := `(;GM[511]SZ[15]PB[Black]
+		;B[h8]C[first (main line)];W[g7-i9]
+		(;B[a1-a2];W[b1-b2])
+		(;B[c1-c2]))`
+	record, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Size != 15 || record.Black != "Black" || len(record.Moves) != 4 ||
+		record.Moves[0].Comment != "first (main line)" || record.Moves[2].Move != parseMove(t, "a1-a2") {
+		fmt.Printf("%#v\n", record)
+		t.Fail()
+	}
+	b, game, err := record.Play(8)
+	if err != nil || b.Width() != 15 || game.Turn() != common.First {
+		fmt.Println(err, &b)
+		t.Fail()
+	}
+
+	record, err = Parse("(;SiZe[15]C[start];C[before];B[h8]C[h8];C[after])")
+	if err != nil || record.Size != 15 || record.Comment != "start\nbefore" || len(record.Moves) != 1 ||
+		record.Moves[0].Comment != "h8\nafter" {
+		fmt.Printf("%v %#v\n", err, record)
+		t.Fail()
+	}
+
+	for _, text := range []string{
+		"",
+		"(;gm[511])",
+		"(;GM[511]",
+		"(;GM[1]SZ[19])",
+		"(;GM[511]SZ[20])",
+		"(;GM[511];W[j10])",
+		"(;GM[511];B[z10])",
+		"(;GM[511]C[unterminated)",
+	} {
+		if _, err := Parse(text); err == nil {
+			fmt.Printf("%q parsed\n", text)
+			t.Fail()
+		}
+	}
+
+	record, _ = Parse("(;GM[511];B[j10];W[j10-j11])")
+	if _, _, err := record.Play(8); !errors.Is(err, connect.ErrOccupied) {
+		fmt.Println(err)
+		t.Fail()
+	}
+}
+
+func TestEngineGame(t *testing.T) {
+	b := connect6.MakeBoard()
+	game := connect6.MakeGame(8)
+	tr := tree.NewTree[*connect6.Connect6, *board.Board, connect6.Move](8)
+	record := &Record{Size: 19, Black: "monte", White: "monte"}
+	for range 6 {
+		decision, _, _ := tr.Search(context.Background(), &b, &game, tree.Limits{Simulations: 5})
+		game.PlayMove(&b, decision.Move)
+		tr.CommitMove(decision.Move)
+		record.Moves = append(record.Moves, Move{Move: decision.Move, Comment: decision.String()})
+	}
+	record.Result = Result(&b)
+
+	parsed, err := Read(strings.NewReader(record.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, replayedGame, err := parsed.Play(8)
+	if err != nil || replayed.Hash() != b.Hash() {
+		fmt.Println(err, &replayed, &b)
+		t.Fail()
+	}
+	tr = tree.NewTree[*connect6.Connect6, *board.Board, connect6.Move](8)
+	if _, _, ok := tr.Search(context.Background(), &replayed, &replayedGame, tree.Limits{Simulations: 5}); !ok {
+		t.Fail()
+	}
+}
@@ -0,0 +1,139 @@
+package tree
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	. "monte/common"
+)
+
+// Pruning selects the nodes written by WriteDOT and WriteJSON. Depth is the
+// deepest level below the root and Sims the fewest simulations of a node.
+// Zero values keep all nodes.
+type Pruning struct {
+	Depth int
+	Sims  int
+}
+
+func (pruning Pruning) keeps(n node, depth int) bool {
+	return (pruning.Depth == 0 || depth <= pruning.Depth) && int(n.nSims) >= pruning.Sims
+}
+
+// ExportNode is a node written by WriteJSON. Value is the mean simulation
+// value from the point of view of the player who played the move, and
+// Decided is "win", "loss" or "draw" once the value is proven. Pruned counts
+// the children left out. With transpositions the children of a node can be
+// shared: they are written once, and the other nodes sharing them name that
+// node by its ID in SharedWith.
+type ExportNode struct {
+	ID         int32        `json:"id"`
+	Move       string       `json:"move,omitempty"`
+	NSims      int32        `json:"nSims"`
+	Value      float64      `json:"value"`
+	Decided    string       `json:"decided,omitempty"`
+	Children   []ExportNode `json:"children,omitempty"`
+	Pruned     int          `json:"pruned,omitempty"`
+	SharedWith *int32       `json:"sharedWith,omitempty"`
+}
+
+// WriteJSON writes the tree as nested ExportNodes, the root first.
+func (tree *Tree[game, board, move]) WriteJSON(w io.Writer, pruning Pruning) error {
+	owners := map[int32]int32{}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tree.exportNode(0, 0, pruning, owners))
+}
+
+// exportNode converts the node and its children. owners maps the first child
+// of every exported range of children to the node that was exported with it.
+func (tree *Tree[game, board, move]) exportNode(idx int32, depth int, pruning Pruning, owners map[int32]int32) ExportNode {
+	n := tree.nodes[idx]
+	result := ExportNode{ID: idx, NSims: n.nSims}
+	result.Value, result.Decided = exportValue(n)
+	if idx != 0 {
+		result.Move = tree.moves[idx].String()
+	}
+	if n.firstChild == 0 {
+		return result
+	}
+	if pruning.Depth > 0 && depth >= pruning.Depth {
+		result.Pruned = int(n.lastChild - n.firstChild)
+		return result
+	}
+	if owner, ok := owners[n.firstChild]; ok {
+		result.SharedWith = &owner
+		return result
+	}
+	owners[n.firstChild] = idx
+	for childIdx := n.firstChild; childIdx < n.lastChild; childIdx++ {
+		if !pruning.keeps(tree.nodes[childIdx], depth+1) {
+			result.Pruned++
+			continue
+		}
+		result.Children = append(result.Children, tree.exportNode(childIdx, depth+1, pruning, owners))
+	}
+	return result
+}
+
+func exportValue(n node) (float64, string) {
+	switch {
+	case n.value.IsWin():
+		return 1, "win"
+	case n.value.IsLoss():
+		return -1, "loss"
+	case n.value.IsDraw():
+		return 0, "draw"
+	case n.nSims == 0:
+		return 0, ""
+	}
+	return float64(n.value / Value(n.nSims)), ""
+}
+
+// WriteDOT writes the tree as a Graphviz digraph. Every node shows its move,
+// simulations and value; proven wins are green, losses red and draws gray.
+// Nodes with shared children are all linked to the same child nodes.
+func (tree *Tree[game, board, move]) WriteDOT(w io.Writer, pruning Pruning) error {
+	buf := bufio.NewWriter(w)
+	buf.WriteString("digraph tree {\n\tnode [shape=box, fontname=\"monospace\"];\n")
+	tree.writeDOTNode(buf, 0, 0, pruning, map[int32]bool{})
+	buf.WriteString("}\n")
+	return buf.Flush()
+}
+
+func (tree *Tree[game, board, move]) writeDOTNode(buf *bufio.Writer, idx int32, depth int, pruning Pruning, written map[int32]bool) {
+	written[idx] = true
+	n := tree.nodes[idx]
+	label := "root"
+	if idx != 0 {
+		label = tree.moves[idx].String()
+	}
+	value, decided := exportValue(n)
+	stats := fmt.Sprintf("n: %d v: %.3f", n.nSims, value)
+	if decided != "" {
+		stats = fmt.Sprintf("n: %d %s", n.nSims, decided)
+	}
+	fmt.Fprintf(buf, "\tn%d [label=\"%s\\n%s\"%s];\n", idx, dotEscape(label), stats, dotColors[decided])
+
+	for childIdx := n.firstChild; childIdx < n.lastChild; childIdx++ {
+		if !pruning.keeps(tree.nodes[childIdx], depth+1) {
+			continue
+		}
+		fmt.Fprintf(buf, "\tn%d -> n%d;\n", idx, childIdx)
+		if !written[childIdx] {
+			tree.writeDOTNode(buf, childIdx, depth+1, pruning, written)
+		}
+	}
+}
+
+var dotColors = map[string]string{
+	"win":  ", color=green",
+	"loss": ", color=red",
+	"draw": ", color=gray",
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
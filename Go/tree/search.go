@@ -0,0 +1,212 @@
+package tree
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Limits bounds a search. Zero values mean no limit.
+type Limits struct {
+	Duration    time.Duration
+	Simulations int
+	Nodes       int
+	Memory      int
+}
+
+type StopReason int
+
+const (
+	StopCanceled StopReason = iota
+	StopDecided
+	StopBestMoveFixed
+	StopDuration
+	StopSimulations
+	StopNodes
+	StopMemory
+)
+
+func (reason StopReason) String() string {
+	switch reason {
+	case StopCanceled:
+		return "canceled"
+	case StopDecided:
+		return "decided"
+	case StopBestMoveFixed:
+		return "best-move-fixed"
+	case StopDuration:
+		return "duration"
+	case StopSimulations:
+		return "simulations"
+	case StopNodes:
+		return "nodes"
+	case StopMemory:
+		return "memory"
+	}
+	panic("StopReason.String()")
+}
+
+type Stats struct {
+	Simulations int
+	Nodes       int
+	Memory      int
+	Duration    time.Duration
+	Reason      StopReason
+}
+
+// Search expands the tree until ctx is done, one of the limits is reached,
+// the root is decided or the best move cannot change within the remaining
+// simulations. With more than one worker it runs them as goroutines that
+// simulate until the search stops. Like BestMove it returns false when there
+// is no move, for example when ctx is done before the first simulation.
+func (tree *Tree[game, board, move]) Search(ctx context.Context, b board, g game, limits Limits) (Decision[move], Stats, bool) {
+	start := time.Now()
+	if limits.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Duration)
+		defer cancel()
+	}
+
+	stats := Stats{}
+	if tree.workers == 1 {
+		for {
+			if reason, done := tree.stop(ctx, limits, &stats, 0); done {
+				stats.Reason = reason
+				break
+			}
+			if tree.Expand(b, g) {
+				stats.Simulations++
+			}
+		}
+	} else {
+		tree.searchShared(ctx, b, g, limits, &stats)
+	}
+	stats.Duration = time.Since(start)
+
+	decision, ok := tree.BestMove()
+	return decision, stats, ok
+}
+
+// searchShared runs the workers of a parallel search. Every worker checks the
+// limits under the tree mutex before it starts a simulation, and once one of
+// them stops the others follow. running counts the simulations that were
+// started and are not backed up yet.
+func (tree *Tree[game, board, move]) searchShared(ctx context.Context, b board, g game, limits Limits, stats *Stats) {
+	running, stopped := 0, false
+	wg := sync.WaitGroup{}
+	for range tree.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				tree.mutex.Lock()
+				if !stopped {
+					stats.Reason, stopped = tree.stop(ctx, limits, stats, running)
+				}
+				if stopped {
+					tree.mutex.Unlock()
+					return
+				}
+				running++
+				b, g := b.Copy(), tree.copyGame(g)
+				tree.mutex.Unlock()
+
+				expanded := tree.expandShared(b, g)
+				tree.mutex.Lock()
+				running--
+				if expanded {
+					stats.Simulations++
+				}
+				tree.mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	tree.validate()
+}
+
+// stop checks the limits of the search. The simulations that are running
+// count against the simulation limit but may still change the best move.
+func (tree *Tree[game, board, move]) stop(ctx context.Context, limits Limits, stats *Stats, running int) (StopReason, bool) {
+	stats.Nodes = len(tree.nodes)
+	stats.Memory = tree.Memory()
+	if tree.decided() {
+		return StopDecided, true
+	}
+	if err := ctx.Err(); err != nil {
+		if err == context.DeadlineExceeded && limits.Duration > 0 {
+			return StopDuration, true
+		}
+		return StopCanceled, true
+	}
+	if limits.Simulations > 0 {
+		if stats.Simulations+running >= limits.Simulations {
+			return StopSimulations, true
+		}
+		if tree.bestMoveFixed(limits.Simulations - stats.Simulations) {
+			return StopBestMoveFixed, true
+		}
+	}
+	if limits.Nodes > 0 && stats.Nodes >= limits.Nodes {
+		return StopNodes, true
+	}
+	if limits.Memory > 0 && stats.Memory >= limits.Memory {
+		return StopMemory, true
+	}
+	return 0, false
+}
+
+// bestMoveFixed reports whether the final move policy selects the same root
+// child whatever the remaining simulations do. That is the case when it is
+// the only move that is not lost, or, for the policies that select the most
+// simulated child, when no other child can catch up with it.
+func (tree *Tree[game, board, move]) bestMoveFixed(remaining int) bool {
+	root := tree.nodes[0]
+	if root.firstChild == 0 {
+		return false
+	}
+	best := tree.bestChild(0, tree.finalMove)
+	open := 0
+	for idx := root.firstChild; idx < root.lastChild; idx++ {
+		if !tree.nodes[idx].value.IsLoss() {
+			open++
+		}
+	}
+	if open == 1 && !tree.nodes[best].value.IsDecided() {
+		return true
+	}
+	if tree.finalMove != MaxVisits && tree.finalMove != MaxRobust {
+		return false
+	}
+	for idx := root.firstChild; idx < root.lastChild; idx++ {
+		child := tree.nodes[idx]
+		if idx == best || child.value.IsLoss() {
+			continue
+		}
+		nSims := int(child.nSims)
+		if !child.value.IsDecided() {
+			nSims += remaining
+		}
+		if nSims >= int(tree.nodes[best].nSims) {
+			return false
+		}
+	}
+	return true
+}
+
+// Memory returns the approximate number of bytes used by the tree nodes, moves
+// and transposition table entries, including the trees kept for UndoMove.
+func (tree *Tree[game, board, move]) Memory() int {
+	result := memory(tree.nodes, tree.moves, tree.table)
+	for _, snapshot := range tree.history {
+		result += memory(snapshot.nodes, snapshot.moves, snapshot.table)
+	}
+	return result
+}
+
+func memory[move any](nodes []node, moves []move, table map[uint64]int32) int {
+	var m move
+	return cap(nodes)*int(unsafe.Sizeof(node{})) + cap(moves)*int(unsafe.Sizeof(m)) +
+		len(table)*int(unsafe.Sizeof(uint64(0))+unsafe.Sizeof(int32(0)))
+}
@@ -0,0 +1,89 @@
+package tree
+
+import (
+	"math"
+
+	. "monte/common"
+)
+
+// Candidate holds the statistics of a child considered during selection.
+// Value and Squares are the sums of the simulation values and of their squares
+// from the point of view of the player choosing the child. Prior is the share
+// of the child in the softmax over the values returned by Game.TopMoves.
+type Candidate struct {
+	NSims   float64
+	Value   float64
+	Squares float64
+	Prior   float64
+}
+
+func (c Candidate) Mean() float64 {
+	return c.Value / c.NSims
+}
+
+// Selection scores the children of a node. The undecided child with the
+// highest score is expanded.
+type Selection interface {
+	Score(parentSims float64, child Candidate, expFactor float64) float64
+}
+
+type UCB1 struct{}
+
+func (UCB1) Score(parentSims float64, child Candidate, expFactor float64) float64 {
+	return child.Mean() + expFactor*math.Sqrt(math.Log(parentSims)/child.NSims)
+}
+
+// UCB1Tuned bounds the exploration term by the variance of the child values.
+// Values lie in [-1, 1], so the variance is bounded by 1 rather than by 1/4.
+type UCB1Tuned struct{}
+
+func (UCB1Tuned) Score(parentSims float64, child Candidate, expFactor float64) float64 {
+	mean := child.Mean()
+	logParentSims := math.Log(parentSims)
+	variance := child.Squares/child.NSims - mean*mean + math.Sqrt(2*logParentSims/child.NSims)
+	return mean + expFactor*math.Sqrt(logParentSims/child.NSims*min(1, variance))
+}
+
+type PUCT struct{}
+
+func (PUCT) Score(parentSims float64, child Candidate, expFactor float64) float64 {
+	return child.Mean() + expFactor*child.Prior*math.Sqrt(parentSims)/(1+child.NSims)
+}
+
+// ProgressiveBias adds to UCB1 a prior term that fades as the child gets
+// simulated.
+type ProgressiveBias struct {
+	Weight float64
+}
+
+func (p ProgressiveBias) Score(parentSims float64, child Candidate, expFactor float64) float64 {
+	return UCB1{}.Score(parentSims, child, expFactor) + p.Weight*child.Prior/(child.NSims+1)
+}
+
+// priors sets the prior of every child to its share of the softmax over the
+// child values, counting a win as 1, a draw as 0 and a loss as -1.
+func priors(children []node) {
+	maxValue := math.Inf(-1)
+	for i := range children {
+		maxValue = max(maxValue, priorValue(children[i].value))
+	}
+	sum := 0.0
+	for i := range children {
+		sum += math.Exp(priorValue(children[i].value) - maxValue)
+	}
+	for i := range children {
+		children[i].prior = float32(math.Exp(priorValue(children[i].value)-maxValue) / sum)
+	}
+}
+
+func priorValue(value Value) float64 {
+	switch {
+	case value.IsWin():
+		return 1
+	case value.IsLoss():
+		return -1
+	case value.IsDraw():
+		return 0
+	}
+	return float64(value)
+}
@@ -0,0 +1,58 @@
+package tree
+
+import . "monte/common"
+
+// Hasher is implemented by boards that can identify their position. Equal
+// hashes must mean equal positions with the same side to move.
+type Hasher interface {
+	Hash() uint64
+}
+
+// SetTranspositions enables the transposition table. A leaf that reaches a
+// position already expanded elsewhere in the tree shares the children of that
+// node instead of generating its own, so the tree becomes a DAG and the
+// simulations of a position are reused by every path leading to it. Each node
+// keeps the simulations that passed through it, and proven values are picked
+// up from the shared children whenever the search passes through a node.
+// The board has to implement Hasher.
+func (tree *Tree[game, board, move]) SetTranspositions(enabled bool) {
+	if enabled {
+		tree.table = map[uint64]int32{}
+	} else {
+		tree.table = nil
+	}
+}
+
+func (tree *Tree[game, board, move]) hash(b board) uint64 {
+	hasher, ok := any(b).(Hasher)
+	if !ok {
+		panic("Transpositions require a board that implements Hash().")
+	}
+	return hasher.Hash()
+}
+
+// transposition returns the index of an expanded node for the position.
+func (tree *Tree[game, board, move]) transposition(hash uint64, leafIdx int32) (int32, bool) {
+	idx, ok := tree.table[hash]
+	if !ok || idx == leafIdx || tree.nodes[idx].firstChild == 0 {
+		return 0, false
+	}
+	return idx, true
+}
+
+// expandLeaf either shares the children of a transposition with the leaf or
+// adds the top moves as its children, in which case it returns true.
+func (tree *Tree[game, board, move]) expandLeaf(hash uint64, leafIdx int32, topMoves []MoveValue[move]) bool {
+	if tree.table == nil {
+		tree.addChildren(leafIdx, topMoves)
+		return true
+	}
+	if idx, ok := tree.transposition(hash, leafIdx); ok {
+		tree.nodes[leafIdx].firstChild = tree.nodes[idx].firstChild
+		tree.nodes[leafIdx].lastChild = tree.nodes[idx].lastChild
+		return false
+	}
+	tree.addChildren(leafIdx, topMoves)
+	tree.table[hash] = leafIdx
+	return true
+}
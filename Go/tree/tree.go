@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"math/rand/v2"
+	"sync"
 
 	. "monte/common"
 )
@@ -11,57 +13,152 @@ import (
 // type Board[move Equatable[move], self any] interface {
 type Board[self any, move any] interface {
 	Copy() self
-	Rollout(Turn, int) Value
 }
 
-type Game[board Board[board, move], move Equatable[move]] interface {
+type Game[self any, board Board[board, move], move Equatable[move]] interface {
+	Copy() self
 	TopMoves(board, *[]MoveValue[move])
-	PlayMove(move)
+	PlayMove(board, move) (Result, error)
 	ExpFactor() float64
 }
 
-type Tree[board Board[board, move], move Equatable[move]] struct {
-	nodes    []node
-	moves    []move
-	topMoves []MoveValue[move]
+type Tree[game Game[game, board, move], board Board[board, move], move Equatable[move]] struct {
+	nodes     []node
+	moves     []move
+	topMoves  []MoveValue[move]
+	maxMoves  int
+	workers   int
+	finalMove FinalMove
+	selection Selection
+	table     map[uint64]int32
+	path      []int32
+	history   []snapshot[move]
+	undos     int
+	rand      *rand.Rand
+	mutex     sync.Mutex
+}
+
+// snapshot is a tree as it was before a CommitMove.
+type snapshot[move any] struct {
+	nodes []node
+	moves []move
+	table map[uint64]int32
 }
 
 type node struct {
-	firstChild int32
-	lastChild  int32
-	nSims      int32
-	value      Value
+	firstChild  int32
+	lastChild   int32
+	nSims       int32
+	virtualLoss int32
+	value       Value
+	squares     Value
+	prior       float32
 }
 
-func NewTree[board Board[board, move], move Equatable[move]]() *Tree[board, move] {
+func NewTree[game Game[game, board, move], board Board[board, move], move Equatable[move]](maxMoves int) *Tree[game, board, move] {
 	var m move
-	return &Tree[board, move]{
-		nodes: []node{{}},
-		moves: []move{m},
+	return &Tree[game, board, move]{
+		nodes:     []node{{}},
+		moves:     []move{m},
+		topMoves:  make([]MoveValue[move], 0, maxMoves),
+		maxMoves:  maxMoves,
+		workers:   1,
+		selection: UCB1{},
+	}
+}
+
+// SetUndos sets how many committed moves can be taken back by UndoMove. Every
+// one of them keeps the whole tree as it was before the move was committed,
+// so there are none by default.
+func (tree *Tree[game, board, move]) SetUndos(undos int) {
+	tree.undos = max(0, undos)
+	if len(tree.history) > tree.undos {
+		tree.history = tree.history[len(tree.history)-tree.undos:]
+	}
+}
+
+// UndoMove steps back to the tree as it was before the last CommitMove. The
+// simulations made after that commit are lost. It returns false when there is
+// no move to take back.
+func (tree *Tree[game, board, move]) UndoMove() bool {
+	if len(tree.history) == 0 {
+		return false
 	}
+	last := tree.history[len(tree.history)-1]
+	tree.history = tree.history[:len(tree.history)-1]
+	tree.nodes = last.nodes
+	tree.moves = last.moves
+	tree.table = last.table
+	tree.validate()
+	return true
+}
+
+func (tree *Tree[game, board, move]) SetSelection(selection Selection) {
+	tree.selection = selection
+}
+
+// SetWorkers sets the number of goroutines Search runs. With more than one
+// worker the tree is searched in parallel, using virtual loss to spread the
+// workers over different paths.
+func (tree *Tree[game, board, move]) SetWorkers(workers int) {
+	tree.workers = max(1, workers)
+}
+
+func (tree *Tree[game, board, move]) Workers() int {
+	return tree.workers
+}
+
+// Randomized is implemented by games that use random numbers, for example in
+// their rollouts. With a seed the tree hands every simulation a generator of
+// its own.
+type Randomized interface {
+	SetRand(*rand.Rand)
 }
 
-func (tree *Tree[board, move]) Expand(b board, game Game[board, move]) {
-	if !tree.nodes[0].value.IsDecided() {
-		tree.expand(b.Copy(), game, 0)
-		// tree.validate() // TODO: implement
+// SetSeed makes the search reproducible: the same seed, position and number
+// of simulations give the same tree and best move. The order of the
+// simulations of parallel workers still depends on scheduling.
+func (tree *Tree[game, board, move]) SetSeed(seed uint64) {
+	tree.rand = rand.New(rand.NewPCG(seed, seed))
+}
+
+// copyGame copies the game for a simulation.
+func (tree *Tree[game, board, move]) copyGame(g game) game {
+	g = g.Copy()
+	if randomized, ok := any(g).(Randomized); ok && tree.rand != nil {
+		randomized.SetRand(rand.New(rand.NewPCG(tree.rand.Uint64(), tree.rand.Uint64())))
 	}
+	return g
+}
 
-	// undecided := 0
-	// for i := root.firstChild; i < root.lastChild; i++ {
-	// 	child := tree.nodes[i]
-	// 	if !child.value.IsDecided() {
-	// 		if child.nSims > 1 {
-	// 			undecided++
-	// 		} else {
-	// 			return root.Decision(), false
-	// 		}
-	// 	}
-	// }
-	// return root.Decision(), undecided == 1
+// decided reports whether the search of the root is over. A root that was
+// proven as a leaf, a draw left after a committed move, still gets expanded
+// so that there is a move to play.
+func (tree *Tree[game, board, move]) decided() bool {
+	root := tree.nodes[0]
+	return root.value.IsDecided() && root.firstChild != 0
 }
 
-func (tree *Tree[board, move]) CommitMove(toPlay move) {
+// Expand runs one simulation and reports whether it ran, which it does not
+// once the root is decided. Parallel simulations are run by Search.
+func (tree *Tree[game, board, move]) Expand(b board, g game) bool {
+	if tree.decided() {
+		return false
+	}
+	tree.expand(b.Copy(), tree.copyGame(g))
+	tree.validate()
+	return true
+}
+
+func (tree *Tree[game, board, move]) CommitMove(toPlay move) {
+	if tree.undos > 0 {
+		if len(tree.history) == tree.undos {
+			copy(tree.history, tree.history[1:])
+			tree.history = tree.history[:len(tree.history)-1]
+		}
+		tree.history = append(tree.history, snapshot[move]{tree.nodes, tree.moves, tree.table})
+	}
+
 	idx := int32(-1)
 	root := tree.nodes[0]
 	for childIdx := root.firstChild; childIdx < root.lastChild; childIdx++ {
@@ -74,6 +171,11 @@ func (tree *Tree[board, move]) CommitMove(toPlay move) {
 	if idx != -1 {
 		newNodes := []node{tree.nodes[idx]}
 		newMoves := []move{tree.moves[idx]}
+		// With transpositions several nodes share a range of children, which
+		// is copied only once. newIdxs maps the copied nodes to their new
+		// indexes so that the table can be rebuilt.
+		newRanges := map[int32]int32{}
+		newIdxs := map[int32]int32{idx: 0}
 		newIdx := 0
 		for newIdx < len(newNodes) {
 			oldFirstChild := newNodes[newIdx].firstChild
@@ -82,6 +184,18 @@ func (tree *Tree[board, move]) CommitMove(toPlay move) {
 				newIdx++
 				continue
 			}
+			if tree.table != nil {
+				if newFirstChild, ok := newRanges[oldFirstChild]; ok {
+					newNodes[newIdx].firstChild = newFirstChild
+					newNodes[newIdx].lastChild = newFirstChild + oldLastChild - oldFirstChild
+					newIdx++
+					continue
+				}
+				newRanges[oldFirstChild] = int32(len(newNodes))
+				for i := oldFirstChild; i < oldLastChild; i++ {
+					newIdxs[i] = int32(len(newNodes)) + i - oldFirstChild
+				}
+			}
 			newNodes[newIdx].firstChild = int32(len(newNodes))
 			newNodes = append(newNodes, tree.nodes[oldFirstChild:oldLastChild]...)
 			newMoves = append(newMoves, tree.moves[oldFirstChild:oldLastChild]...)
@@ -90,37 +204,164 @@ func (tree *Tree[board, move]) CommitMove(toPlay move) {
 		}
 		tree.nodes = newNodes
 		tree.moves = newMoves
+		if tree.table != nil {
+			table := map[uint64]int32{}
+			for hash, oldIdx := range tree.table {
+				if newIdx, ok := newIdxs[oldIdx]; ok {
+					table[hash] = newIdx
+				}
+			}
+			tree.table = table
+		}
+		tree.validate()
 
 		return
 	}
 
+	if tree.undos > 0 {
+		tree.nodes = nil
+		tree.moves = nil
+	}
 	tree.nodes = tree.nodes[:0]
 	tree.nodes = append(tree.nodes, node{})
 	tree.moves = tree.moves[:0]
 	tree.moves = append(tree.moves, toPlay)
+	if tree.table != nil {
+		tree.table = map[uint64]int32{}
+	}
 }
 
-func (tree *Tree[board, move]) BestMove() move { // TODO: return forced move indicator
-	root := tree.nodes[0]
-	bestValue := tree.nodes[root.firstChild].value
-	bestMove := tree.moves[root.firstChild]
-	if bestValue.IsDraw() {
-		bestValue = 0
+// Decision describes a root move. Value is the mean simulation value from the
+// point of view of the player to move, or Win, Loss or Draw once the move is
+// proven. A move is forced when every other move is a proven loss.
+type Decision[move any] struct {
+	Move   move
+	Value  Value
+	NSims  int
+	Forced bool
+}
+
+// Proven reports whether the value of the move is a proven Win, Loss or Draw.
+func (d Decision[move]) Proven() bool {
+	return d.Value.IsDecided()
+}
+
+func (d Decision[move]) String() string {
+	return fmt.Sprintf("%v v: %v n: %d forced: %v", d.Move, d.Value, d.NSims, d.Forced)
+}
+
+// FinalMove selects the move to play once the search is over. Proven wins are
+// always preferred and proven losses are only played when nothing else is left.
+type FinalMove int
+
+const (
+	// MaxValue selects the child with the highest mean value.
+	MaxValue FinalMove = iota
+	// MaxVisits selects the child with the most simulations, the robust child.
+	MaxVisits
+	// MaxRobust selects the child that has both the highest mean value and the
+	// most simulations, falling back to MaxVisits when there is no such child.
+	MaxRobust
+	// SecureChild selects the child with the highest lower confidence bound.
+	SecureChild
+)
+
+const secureFactor = 1
+
+func (policy FinalMove) String() string {
+	switch policy {
+	case MaxValue:
+		return "max-value"
+	case MaxVisits:
+		return "max-visits"
+	case MaxRobust:
+		return "max-robust"
+	case SecureChild:
+		return "secure-child"
 	}
-	for idx := root.firstChild + 1; idx < root.lastChild; idx++ {
-		value := tree.nodes[idx].value
-		if value.IsDraw() {
-			value = 0
+	panic("FinalMove.String()")
+}
+
+func (tree *Tree[game, board, move]) SetFinalMove(policy FinalMove) {
+	tree.finalMove = policy
+}
+
+// BestMove selects the move to play with the final move policy. It returns
+// false when the root has not been expanded and there is no move yet.
+func (tree *Tree[game, board, move]) BestMove() (Decision[move], bool) {
+	if tree.nodes[0].firstChild == 0 {
+		return Decision[move]{}, false
+	}
+	return tree.decision(tree.bestChild(0, tree.finalMove)), true
+}
+
+// bestChild selects a child of the node with the policy. Children without
+// simulations are only selected when there is nothing else.
+func (tree *Tree[game, board, move]) bestChild(parentIdx int32, policy FinalMove) int32 {
+	parent := tree.nodes[parentIdx]
+	maxValueIdx, maxVisitsIdx, secureIdx := int32(-1), int32(-1), int32(-1)
+	maxValue, maxVisits, maxSecure := 0.0, int32(0), 0.0
+	for idx := parent.firstChild; idx < parent.lastChild; idx++ {
+		child := tree.nodes[idx]
+		if child.value.IsWin() {
+			return idx
+		} else if child.value.IsLoss() || child.nSims == 0 {
+			continue
+		}
+		value := 0.0
+		if !child.value.IsDraw() {
+			value = float64(child.value) / float64(child.nSims)
+		}
+		if maxValueIdx == -1 || value > maxValue {
+			maxValue, maxValueIdx = value, idx
+		}
+		if maxVisitsIdx == -1 || child.nSims > maxVisits {
+			maxVisits, maxVisitsIdx = child.nSims, idx
+		}
+		secure := value - secureFactor/math.Sqrt(float64(child.nSims))
+		if secureIdx == -1 || secure > maxSecure {
+			maxSecure, secureIdx = secure, idx
+		}
+	}
+	if maxValueIdx == -1 {
+		return parent.firstChild
+	}
+
+	switch policy {
+	case MaxVisits:
+		return maxVisitsIdx
+	case MaxRobust:
+		if tree.nodes[maxValueIdx].nSims == maxVisits {
+			return maxValueIdx
 		}
-		if bestValue < 0 {
-			bestValue = value
-			bestMove = tree.moves[idx]
+		return maxVisitsIdx
+	case SecureChild:
+		return secureIdx
+	}
+	return maxValueIdx
+}
+
+func (tree *Tree[game, board, move]) decision(idx int32) Decision[move] {
+	root := tree.nodes[0]
+	child := tree.nodes[idx]
+	result := Decision[move]{
+		Move:   tree.moves[idx],
+		Value:  child.value,
+		NSims:  int(child.nSims),
+		Forced: !child.value.IsLoss(),
+	}
+	if !child.value.IsDecided() {
+		result.Value = child.value / Value(child.nSims)
+	}
+	for i := root.firstChild; i < root.lastChild; i++ {
+		if i != idx && !tree.nodes[i].value.IsLoss() {
+			result.Forced = false
 		}
 	}
-	return bestMove
+	return result
 }
 
-func (tree *Tree[board, move]) DebugAvailableMoves() string {
+func (tree *Tree[game, board, move]) DebugAvailableMoves() string {
 	buf := &bytes.Buffer{}
 	root := tree.nodes[0]
 	fmt.Fprintf(buf, "%s: d: %v v: %v n: %d\n", tree.moves[0].String(), root.value, root.value, root.nSims)
@@ -131,76 +372,276 @@ func (tree *Tree[board, move]) DebugAvailableMoves() string {
 	return buf.String()
 }
 
-func (tree *Tree[board, move]) expand(b board, game Game[board, move], parentIdx int32) {
-	parent := &tree.nodes[parentIdx]
+// expand is a single simulation: it selects a path to a leaf, expands the leaf
+// and backs up the result.
+func (tree *Tree[game, board, move]) expand(b board, g game) {
+	expFactor := g.ExpFactor()
+	path := append(tree.path[:0], 0)
+	idx := int32(0)
+	for tree.nodes[idx].firstChild != 0 {
+		if tree.table != nil {
+			tree.decide(idx)
+			if tree.nodes[idx].value.IsDecided() {
+				break
+			}
+		}
+		idx = tree.selectChild(idx, expFactor)
+		playMove(b, g, tree.moves[idx])
+		path = append(path, idx)
+	}
+	tree.path = path
 
-	if parent.firstChild == 0 {
-		game.TopMoves(b, &tree.topMoves)
-		if len(tree.topMoves) == 0 {
-			panic("Function top_moves(game, ...) returns empty result.")
+	leaf := tree.nodes[idx]
+	fresh := false
+	if leaf.firstChild == 0 {
+		hash := uint64(0)
+		if tree.table != nil {
+			hash = tree.hash(b)
+		}
+		if _, ok := tree.transposition(hash, idx); !ok {
+			g.TopMoves(b, &tree.topMoves)
 		}
+		fresh = tree.expandLeaf(hash, idx, tree.topMoves)
+	}
+	tree.backup(path, leaf, fresh)
+}
 
-		parent.firstChild = int32(len(tree.nodes))
-		parent.lastChild = int32(len(tree.nodes) + len(tree.topMoves))
-		for _, child := range tree.topMoves {
-			tree.nodes = append(tree.nodes, node{
-				nSims: 1,
-				value: child.Value,
-			})
-			tree.moves = append(tree.moves, child.Move)
+// playMove plays a move of the tree. The moves come from Game.TopMoves, so a
+// rejected one is a bug in the game.
+func playMove[game Game[game, board, move], board Board[board, move], move Equatable[move]](b board, g game, m move) {
+	if _, err := g.PlayMove(b, m); err != nil {
+		panic(fmt.Sprintf("PlayMove(%v): %v", m, err))
+	}
+}
+
+// expandShared is a single simulation of the parallel search. Selection and
+// backup happen under the tree mutex, while moves are played and the leaf is
+// evaluated outside of it. Nodes on the selected path carry a virtual loss
+// until the simulation is backed up. It returns false when the root is
+// decided and there is nothing to simulate.
+func (tree *Tree[game, board, move]) expandShared(b board, g game) bool {
+	path := []int32{0}
+	moves := []move{}
+
+	tree.mutex.Lock()
+	if tree.decided() {
+		tree.mutex.Unlock()
+		return false
+	}
+	expFactor := g.ExpFactor()
+	idx := int32(0)
+	for tree.nodes[idx].firstChild != 0 {
+		if tree.table != nil {
+			tree.decide(idx)
+			if tree.nodes[idx].value.IsDecided() {
+				break
+			}
+		}
+		idx = tree.selectChild(idx, expFactor)
+		tree.nodes[idx].virtualLoss++
+		path = append(path, idx)
+		moves = append(moves, tree.moves[idx])
+	}
+	isLeaf := tree.nodes[idx].firstChild == 0
+	tree.mutex.Unlock()
+
+	hash := uint64(0)
+	topMoves := make([]MoveValue[move], 0, tree.maxMoves)
+	if isLeaf {
+		for _, m := range moves {
+			playMove(b, g, m)
+		}
+		transposed := false
+		if tree.table != nil {
+			hash = tree.hash(b)
+			tree.mutex.Lock()
+			_, transposed = tree.transposition(hash, idx)
+			tree.mutex.Unlock()
 		}
+		if !transposed {
+			g.TopMoves(b, &topMoves)
+		}
+	}
+
+	tree.mutex.Lock()
+	defer tree.mutex.Unlock()
+	for _, idx := range path[1:] {
+		tree.nodes[idx].virtualLoss--
+	}
+	leaf := tree.nodes[idx]
+	fresh := false
+	if leaf.firstChild == 0 {
+		fresh = tree.expandLeaf(hash, idx, topMoves)
+	}
+	tree.backup(path, leaf, fresh)
+	return true
+}
+
+// backup updates the nodes on the path after its last node has been expanded.
+// Without transpositions every node is recalculated from its children. With
+// transpositions the children of a node may be shared with other nodes, so
+// the change of a freshly expanded leaf is added to its ancestors instead, and
+// proven values are recalculated from the children.
+func (tree *Tree[game, board, move]) backup(path []int32, leaf node, fresh bool) {
+	if tree.table == nil {
+		for i := len(path) - 1; i >= 0; i-- {
+			tree.update(path[i])
+		}
+		return
+	}
+
+	leafIdx := path[len(path)-1]
+	if fresh {
+		tree.update(leafIdx)
 	} else {
-		expFactor := game.ExpFactor()
-		selectedChildIdx := int32(-1)
-		logParentSims := math.Log(float64(parent.nSims))
-		maxV := math.Inf(-1)
-		for idx := parent.firstChild; idx < parent.lastChild; idx++ {
-			child := tree.nodes[idx]
-			if child.value.IsDecided() {
-				continue
-			}
-			v := float64(child.value)/float64(parent.nSims) + expFactor*math.Sqrt(logParentSims/float64(child.nSims))
-			if v > maxV {
-				maxV = v
-				selectedChildIdx = idx
-			}
+		tree.decide(leafIdx)
+	}
+	expanded := tree.nodes[leafIdx]
+	nSims, value, squares := int32(0), Value(0), Value(0)
+	if !expanded.value.IsDecided() {
+		nSims = expanded.nSims - leaf.nSims
+		value = expanded.value - leaf.value
+		squares = expanded.squares - leaf.squares
+	}
+	for i := len(path) - 2; i >= 0; i-- {
+		node := &tree.nodes[path[i]]
+		value = -value
+		if !node.value.IsDecided() {
+			node.nSims += nSims
+			node.value += value
+			node.squares += squares
 		}
+		tree.decide(path[i])
+		if node.value.IsDecided() {
+			nSims, value, squares = 0, 0, 0
+		}
+	}
+}
 
-		game.PlayMove(tree.moves[selectedChildIdx])
-		tree.expand(b, game, selectedChildIdx)
+func (tree *Tree[game, board, move]) addChildren(parentIdx int32, topMoves []MoveValue[move]) {
+	if len(topMoves) == 0 {
+		panic("Function top_moves(game, ...) returns empty result.")
 	}
 
-	parent = &tree.nodes[parentIdx]
+	parent := &tree.nodes[parentIdx]
+	parent.firstChild = int32(len(tree.nodes))
+	parent.lastChild = int32(len(tree.nodes) + len(topMoves))
+	for _, child := range topMoves {
+		squares := Value(0)
+		if !child.Value.IsDecided() {
+			squares = child.Value * child.Value
+		}
+		tree.nodes = append(tree.nodes, node{
+			nSims:   1,
+			value:   child.Value,
+			squares: squares,
+		})
+		tree.moves = append(tree.moves, child.Move)
+	}
+	priors(tree.nodes[len(tree.nodes)-len(topMoves):])
+}
+
+func (tree *Tree[game, board, move]) selectChild(parentIdx int32, expFactor float64) int32 {
+	parent := tree.nodes[parentIdx]
+	selectedChildIdx := int32(-1)
+	parentSims := float64(parent.nSims + parent.virtualLoss)
+	maxV := math.Inf(-1)
+	for idx := parent.firstChild; idx < parent.lastChild; idx++ {
+		child := tree.nodes[idx]
+		if child.value.IsDecided() {
+			continue
+		}
+		v := tree.selection.Score(parentSims, Candidate{
+			NSims:   float64(child.nSims + child.virtualLoss),
+			Value:   float64(child.value) - float64(child.virtualLoss),
+			Squares: float64(child.squares) + float64(child.virtualLoss),
+			Prior:   float64(child.prior),
+		}, expFactor)
+		if v > maxV {
+			maxV = v
+			selectedChildIdx = idx
+		}
+	}
+	return selectedChildIdx
+}
+
+// update recalculates the node from its children. A winning child makes the
+// node lost, a node with all children lost is won, and a node with nothing but
+// draws and losses left is a draw. Otherwise nSims and value are summed over
+// the undecided children.
+func (tree *Tree[game, board, move]) update(parentIdx int32) {
+	parent := &tree.nodes[parentIdx]
 	parent.nSims = int32(0)
-	parent.value = Loss
-	hasDraw := false
+	parent.value = 0
+	parent.squares = 0
+	hasWin, hasDraw, hasUndecided := false, false, false
 	for i := parent.firstChild; i < parent.lastChild; i++ {
 		child := tree.nodes[i]
 		if child.value.IsWin() {
-			parent.value = Loss
-			return
+			hasWin = true
+			continue
 		} else if child.value.IsDraw() {
 			hasDraw = true
 			continue
 		} else if child.value.IsLoss() {
 			continue
 		}
+		hasUndecided = true
 		parent.nSims += child.nSims
 		parent.value += child.value
+		parent.squares += child.squares
 	}
-	parent.value = -parent.value
-	if hasDraw && parent.value < 0 {
-		parent.value = 0
+	switch {
+	case hasWin:
+		parent.value = Loss
+	case !hasUndecided && hasDraw:
+		parent.value = Draw
+	case !hasUndecided:
+		parent.value = Win
+	default:
+		parent.value = -parent.value
+		if hasDraw && parent.value < 0 {
+			parent.value = 0
+		}
+	}
+}
+
+// decide sets the proven value of the node from its children, leaving the
+// node alone while the value is still open.
+func (tree *Tree[game, board, move]) decide(idx int32) {
+	node := &tree.nodes[idx]
+	if node.value.IsDecided() {
+		return
+	}
+	hasDraw, hasUndecided := false, false
+	for i := node.firstChild; i < node.lastChild; i++ {
+		child := tree.nodes[i]
+		if child.value.IsWin() {
+			node.value = Loss
+			return
+		} else if child.value.IsDraw() {
+			hasDraw = true
+		} else if !child.value.IsLoss() {
+			hasUndecided = true
+		}
+	}
+	if hasUndecided {
+		return
+	}
+	if hasDraw {
+		node.value = Draw
+	} else {
+		node.value = Win
 	}
 }
 
-func (tree *Tree[board, move]) String() string {
+func (tree *Tree[game, board, move]) String() string {
 	buf := &bytes.Buffer{}
 	tree.string(buf, 0, 0)
 	return buf.String()
 }
 
-func (tree *Tree[board, move]) string(buf *bytes.Buffer, idx int32, depth int) {
+func (tree *Tree[game, board, move]) string(buf *bytes.Buffer, idx int32, depth int) {
 	buf.WriteRune('\n')
 	for range depth {
 		buf.WriteString("|   ")
@@ -0,0 +1,15 @@
+//go:build debug
+
+package tree
+
+import "fmt"
+
+func (tree *Tree[game, board, move]) validate() {
+	if err := tree.Validate(); err != nil {
+		fmt.Printf("%v\n", err)
+		if len(tree.nodes) < 1000 {
+			fmt.Printf("Tree:%v\n", tree)
+		}
+		panic("### Validation ###")
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !debug
+
+package tree
+
+func (tree *Tree[game, board, move]) validate() {}
@@ -0,0 +1,607 @@
+package tree
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	. "monte/common"
+)
+
+// nim is a toy game for testing the tree: players take one to three stones
+// from a pile and the player who takes the last stone wins. Positions with a
+// multiple of four stones are lost for the player to move.
+type nim struct{}
+
+type nimBoard struct {
+	stones int
+}
+
+type nimMove int8
+
+func (m nimMove) Equal(other nimMove) bool {
+	return m == other
+}
+
+func (m nimMove) String() string {
+	return fmt.Sprintf("take-%d", int(m))
+}
+
+func (b *nimBoard) Copy() *nimBoard {
+	board := *b
+	return &board
+}
+
+func (b *nimBoard) Hash() uint64 {
+	return uint64(b.stones)
+}
+
+func (n *nim) Copy() *nim {
+	return &nim{}
+}
+
+func (n *nim) TopMoves(b *nimBoard, moves *[]MoveValue[nimMove]) {
+	*moves = (*moves)[:0]
+	for take := 1; take <= min(3, b.stones); take++ {
+		value := Value(0)
+		if take == b.stones {
+			value = Win
+		}
+		*moves = append(*moves, MoveValue[nimMove]{Move: nimMove(take), Value: value})
+	}
+}
+
+func (n *nim) PlayMove(b *nimBoard, m nimMove) (Result, error) {
+	b.stones -= int(m)
+	if b.stones == 0 {
+		return Won, nil
+	}
+	return Continue, nil
+}
+
+func (n *nim) ExpFactor() float64 {
+	return 1
+}
+
+func newNimTree() *Tree[*nim, *nimBoard, nimMove] {
+	return NewTree[*nim, *nimBoard, nimMove](3)
+}
+
+func bestMove(tree *Tree[*nim, *nimBoard, nimMove]) Decision[nimMove] {
+	decision, _ := tree.BestMove()
+	return decision
+}
+
+func TestExpand(t *testing.T) {
+	tree := newNimTree()
+	b := &nimBoard{stones: 9}
+	for range 10000 {
+		tree.Expand(b, &nim{})
+	}
+	if !tree.nodes[0].value.IsLoss() {
+		fmt.Println(tree.DebugAvailableMoves())
+		t.Fail()
+	}
+	if bestMove(tree).Move != 1 {
+		fmt.Println(tree.DebugAvailableMoves())
+		t.Fail()
+	}
+	if b.stones != 9 {
+		t.Fail()
+	}
+}
+
+func TestSearchParallel(t *testing.T) {
+	tree := newNimTree()
+	tree.SetWorkers(4)
+	b := &nimBoard{stones: 13}
+	_, stats, _ := tree.Search(context.Background(), b, &nim{}, Limits{Duration: 10 * time.Second})
+	if !tree.nodes[0].value.IsLoss() || stats.Reason != StopDecided || stats.Simulations == 0 {
+		fmt.Println(stats)
+		fmt.Println(tree.DebugAvailableMoves())
+		t.Fail()
+	}
+	if bestMove(tree).Move != 1 {
+		fmt.Println(tree.DebugAvailableMoves())
+		t.Fail()
+	}
+	for _, node := range tree.nodes {
+		if node.virtualLoss != 0 {
+			t.Fail()
+		}
+	}
+}
+
+func TestSearchDecided(t *testing.T) {
+	tree := newNimTree()
+	decision, stats, _ := tree.Search(context.Background(), &nimBoard{stones: 9}, &nim{}, Limits{Duration: time.Second})
+	if decision.Move != 1 || !decision.Value.IsWin() || stats.Reason != StopDecided {
+		fmt.Println(decision, stats)
+		t.Fail()
+	}
+}
+
+func TestSearchDecidedLeaf(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		tree := newNimTree()
+		tree.SetWorkers(workers)
+		tree.nodes[0].value = Draw
+		decision, stats, _ := tree.Search(context.Background(), &nimBoard{stones: 5}, &nim{}, Limits{Simulations: 100})
+		if decision.Move != 1 || tree.nodes[0].firstChild == 0 || stats.Simulations == 0 {
+			fmt.Println(workers, decision, stats)
+			t.Fail()
+		}
+	}
+}
+
+func TestBestMoveForced(t *testing.T) {
+	tree := newNimTree()
+	b := &nimBoard{stones: 9}
+	for !tree.nodes[0].value.IsDecided() {
+		tree.Expand(b, &nim{})
+	}
+	decision := bestMove(tree)
+	if decision.Move != 1 || !decision.Value.IsWin() || !decision.Forced {
+		fmt.Println(decision)
+		t.Fail()
+	}
+
+	tree = newNimTree()
+	b = &nimBoard{stones: 1000}
+	for range 100 {
+		tree.Expand(b, &nim{})
+	}
+	decision = bestMove(tree)
+	if decision.Value.IsDecided() || decision.Forced || decision.NSims < 1 {
+		fmt.Println(decision)
+		t.Fail()
+	}
+
+	tree = newNimTree()
+	if _, ok := tree.BestMove(); ok {
+		t.Fail()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if decision, stats, ok := tree.Search(ctx, b, &nim{}, Limits{}); ok || stats.Reason != StopCanceled {
+		fmt.Println(decision, stats)
+		t.Fail()
+	}
+}
+
+func TestFinalMove(t *testing.T) {
+	tree := newNimTree()
+	tree.nodes = []node{
+		{firstChild: 1, lastChild: 5},
+		{nSims: 10, value: 8},
+		{nSims: 100, value: 50},
+		{nSims: 60, value: 45},
+		{nSims: 1, value: Loss},
+	}
+	tree.moves = []nimMove{0, 1, 2, 3, 4}
+	expected := map[FinalMove]nimMove{MaxValue: 1, MaxVisits: 2, MaxRobust: 2, SecureChild: 3}
+	for policy, m := range expected {
+		tree.SetFinalMove(policy)
+		if bestMove(tree).Move != m {
+			fmt.Println(policy, bestMove(tree))
+			t.Fail()
+		}
+	}
+
+	tree.nodes[2].value = 90
+	tree.SetFinalMove(MaxRobust)
+	if bestMove(tree).Move != 2 {
+		fmt.Println(bestMove(tree))
+		t.Fail()
+	}
+
+	tree.nodes[4].value = Win
+	for policy := range expected {
+		tree.SetFinalMove(policy)
+		if bestMove(tree).Move != 4 {
+			fmt.Println(policy, bestMove(tree))
+			t.Fail()
+		}
+	}
+
+	for i := 1; i < 5; i++ {
+		tree.nodes[i].value = Loss
+	}
+	tree.nodes[3].value = Draw
+	for policy := range expected {
+		tree.SetFinalMove(policy)
+		if bestMove(tree).Move != 3 {
+			fmt.Println(policy, bestMove(tree))
+			t.Fail()
+		}
+	}
+}
+
+func TestPrincipalVariation(t *testing.T) {
+	tree := newNimTree()
+	tree.nodes = []node{
+		{firstChild: 1, lastChild: 4},
+		{nSims: 10, value: 8},
+		{firstChild: 4, lastChild: 6, nSims: 100, value: 50},
+		{nSims: 60, value: Loss},
+		{nSims: 30, value: -10},
+		{firstChild: 6, lastChild: 7, nSims: 70, value: 14},
+		{nSims: 0},
+	}
+	tree.moves = []nimMove{0, 1, 2, 3, 1, 2, 3}
+	if pv := tree.PrincipalVariation(MaxVisits); !slices.Equal(pv, []nimMove{2, 2, 3}) {
+		fmt.Println(pv)
+		t.Fail()
+	}
+	if pv := tree.PrincipalVariation(MaxValue); !slices.Equal(pv, []nimMove{1}) {
+		fmt.Println(pv)
+		t.Fail()
+	}
+	if pv := newNimTree().PrincipalVariation(MaxVisits); len(pv) != 0 {
+		fmt.Println(pv)
+		t.Fail()
+	}
+
+	lines := tree.Lines(0, MaxVisits)
+	if len(lines) != 3 || lines[0].Move != 2 || !slices.Equal(lines[0].PV, []nimMove{2, 2, 3}) || lines[0].NSims != 100 ||
+		lines[1].Move != 1 || !slices.Equal(lines[1].PV, []nimMove{1}) || lines[1].Value != 0.8 ||
+		lines[2].Move != 3 || !lines[2].Proven() || lines[0].Proven() {
+		fmt.Println(lines)
+		t.Fail()
+	}
+	lines = tree.Lines(2, MaxValue)
+	if len(lines) != 2 || lines[0].Move != 1 || lines[1].Move != 2 {
+		fmt.Println(lines)
+		t.Fail()
+	}
+
+	tree.nodes[4].value = Win
+	lines = tree.Lines(1, MaxVisits)
+	if len(lines) != 1 || lines[0].Move != 2 || !slices.Equal(lines[0].PV, []nimMove{2, 1}) {
+		fmt.Println(lines)
+		t.Fail()
+	}
+}
+
+func TestExport(t *testing.T) {
+	tree := newNimTree()
+	tree.nodes = []node{
+		{firstChild: 1, lastChild: 4, nSims: 170, value: -40},
+		{nSims: 10, value: 8},
+		{firstChild: 4, lastChild: 6, nSims: 100, value: 50},
+		{nSims: 60, value: Loss},
+		{nSims: 30, value: -15},
+		{nSims: 70, value: 14},
+	}
+	tree.moves = []nimMove{0, 1, 2, 3, 1, 2}
+
+	buf := &bytes.Buffer{}
+	if err := tree.WriteJSON(buf, Pruning{}); err != nil {
+		t.Fatal(err)
+	}
+	root := ExportNode{}
+	if err := json.Unmarshal(buf.Bytes(), &root); err != nil {
+		t.Fatal(err)
+	}
+	if root.Move != "" || root.NSims != 170 || len(root.Children) != 3 || root.Children[1].Value != 0.5 ||
+		root.Children[2].Decided != "loss" || root.Children[2].Value != -1 || len(root.Children[1].Children) != 2 ||
+		root.Children[1].Children[0].Move != "take-1" || root.Children[1].Children[0].Value != -0.5 {
+		fmt.Println(buf)
+		t.Fail()
+	}
+
+	buf.Reset()
+	tree.WriteJSON(buf, Pruning{Depth: 1, Sims: 20})
+	root = ExportNode{}
+	json.Unmarshal(buf.Bytes(), &root)
+	if len(root.Children) != 2 || root.Pruned != 1 || root.Children[0].Move != "take-2" ||
+		len(root.Children[0].Children) != 0 || root.Children[0].Pruned != 2 {
+		fmt.Println(buf)
+		t.Fail()
+	}
+
+	buf.Reset()
+	if err := tree.WriteDOT(buf, Pruning{Sims: 40}); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, expected := range []string{"digraph tree {", `n0 [label="root\nn: 170 v: -0.235"];`, `n3 [label="take-3\nn: 60 loss", color=red];`, "n2 -> n5;"} {
+		if !strings.Contains(dot, expected) {
+			fmt.Println(dot)
+			t.Fatalf("expected %q", expected)
+		}
+	}
+	if strings.Contains(dot, "n1 ") || strings.Contains(dot, "n4") || !strings.HasSuffix(dot, "}\n") {
+		fmt.Println(dot)
+		t.Fail()
+	}
+
+	tree = newNimTree()
+	tree.SetTranspositions(true)
+	tree.Search(context.Background(), &nimBoard{stones: 9}, &nim{}, Limits{Simulations: 200})
+	buf.Reset()
+	tree.WriteJSON(buf, Pruning{})
+	if !strings.Contains(buf.String(), `"sharedWith"`) {
+		fmt.Println(buf)
+		t.Fail()
+	}
+	buf.Reset()
+	tree.WriteDOT(buf, Pruning{})
+	if nodes := strings.Count(buf.String(), "[label="); nodes != len(tree.nodes) {
+		fmt.Println("expected", len(tree.nodes), "nodes got", nodes)
+		t.Fail()
+	}
+}
+
+func TestSelection(t *testing.T) {
+	for _, selection := range []Selection{UCB1{}, UCB1Tuned{}, PUCT{}, ProgressiveBias{Weight: 1}} {
+		tree := newNimTree()
+		tree.SetSelection(selection)
+		decision, stats, _ := tree.Search(context.Background(), &nimBoard{stones: 9}, &nim{}, Limits{Duration: time.Second})
+		if decision.Move != 1 || stats.Reason != StopDecided {
+			fmt.Printf("%T %v %v\n", selection, decision, stats)
+			t.Fail()
+		}
+	}
+}
+
+func TestSelectionScores(t *testing.T) {
+	steady := Candidate{NSims: 10, Value: 5, Squares: 2.5, Prior: 0.1}
+	noisy := Candidate{NSims: 10, Value: 5, Squares: 10, Prior: 0.1}
+	tuned, ucb1, puct := UCB1Tuned{}, UCB1{}, PUCT{}
+	if tuned.Score(100, steady, 1) >= tuned.Score(100, noisy, 1) {
+		t.Fail()
+	}
+	if ucb1.Score(100, steady, 1) != ucb1.Score(100, noisy, 1) {
+		t.Fail()
+	}
+
+	likely := Candidate{NSims: 10, Value: 5, Prior: 0.9}
+	if puct.Score(100, steady, 1) >= puct.Score(100, likely, 1) {
+		t.Fail()
+	}
+	bias := ProgressiveBias{Weight: 1}
+	if bias.Score(100, steady, 1) >= bias.Score(100, likely, 1) {
+		t.Fail()
+	}
+	likely.NSims, likely.Value = 1000, 500
+	steady.NSims, steady.Value = 1000, 500
+	if bias.Score(100, likely, 1)-bias.Score(100, steady, 1) > 0.001 {
+		t.Fail()
+	}
+}
+
+func TestPriors(t *testing.T) {
+	children := []node{{value: 1}, {value: -1}, {value: Win}, {value: Draw}}
+	priors(children)
+	sum := float32(0)
+	for _, child := range children {
+		sum += child.prior
+	}
+	if sum < 0.999 || sum > 1.001 || children[0].prior != children[2].prior || children[1].prior >= children[3].prior {
+		fmt.Println(children)
+		t.Fail()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tree := newNimTree()
+	tree.SetWorkers(3)
+	b := &nimBoard{stones: 30}
+	tree.Search(context.Background(), b, &nim{}, Limits{Simulations: 300})
+	if err := tree.Validate(); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+	tree.CommitMove(bestMove(tree).Move)
+	if err := tree.Validate(); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+
+	tree.nodes[0].nSims++
+	if tree.Validate() == nil {
+		t.Fail()
+	}
+	tree.nodes[0].nSims--
+
+	tree = newNimTree()
+	tree.Expand(b, &nim{})
+	child := &tree.nodes[tree.nodes[0].firstChild]
+	child.value = Win
+	if tree.Validate() == nil {
+		t.Fail()
+	}
+	tree.nodes[0].value = Loss
+	tree.nodes[0].nSims -= child.nSims
+	if err := tree.Validate(); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+	tree.nodes[0].lastChild = int32(len(tree.nodes) + 1)
+	if tree.Validate() == nil {
+		t.Fail()
+	}
+}
+
+func TestTranspositions(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		tree := newNimTree()
+		tree.SetWorkers(workers)
+		tree.SetTranspositions(true)
+		b := &nimBoard{stones: 41}
+		decision, stats, _ := tree.Search(context.Background(), b, &nim{}, Limits{Duration: time.Second})
+		if decision.Move != 1 || !decision.Value.IsWin() || stats.Reason != StopDecided || stats.Nodes > 3*41+1 {
+			fmt.Println(workers, decision, stats)
+			t.Fail()
+		}
+		if err := tree.Validate(); err != nil {
+			fmt.Println(err)
+			t.Fail()
+		}
+	}
+
+	tree := newNimTree()
+	tree.SetTranspositions(true)
+	b := &nimBoard{stones: 1000}
+	for range 1000 {
+		tree.Expand(b, &nim{})
+	}
+	nodes := len(tree.nodes)
+	tree.CommitMove(2)
+	b.stones -= 2
+	if err := tree.Validate(); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+	if len(tree.nodes) >= nodes || len(tree.table) == 0 {
+		t.Fail()
+	}
+	for range 1000 {
+		tree.Expand(b, &nim{})
+	}
+	if err := tree.Validate(); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+}
+
+func TestUndoMove(t *testing.T) {
+	tree := newNimTree()
+	tree.SetUndos(2)
+	b := &nimBoard{stones: 100}
+	for range 100 {
+		tree.Expand(b, &nim{})
+	}
+	nodes := len(tree.nodes)
+	best := bestMove(tree)
+
+	tree.CommitMove(best.Move)
+	for range 100 {
+		tree.Expand(&nimBoard{stones: 100 - int(best.Move)}, &nim{})
+	}
+	tree.CommitMove(3)
+	tree.CommitMove(3)
+	if !tree.UndoMove() || !tree.UndoMove() || tree.UndoMove() {
+		t.Fail()
+	}
+	if len(tree.nodes) < 100 {
+		t.Fail()
+	}
+	tree.CommitMove(2)
+	tree.SetUndos(1)
+	if !tree.UndoMove() || tree.UndoMove() {
+		t.Fail()
+	}
+
+	tree = newNimTree()
+	for range 100 {
+		tree.Expand(b, &nim{})
+	}
+	tree.CommitMove(bestMove(tree).Move)
+	if tree.UndoMove() {
+		t.Fatal("undo without SetUndos")
+	}
+
+	tree = newNimTree()
+	tree.SetUndos(1)
+	for range 100 {
+		tree.Expand(b, &nim{})
+	}
+	tree.CommitMove(bestMove(tree).Move)
+	if !tree.UndoMove() || len(tree.nodes) != nodes || bestMove(tree) != best {
+		fmt.Println(len(tree.nodes), nodes, bestMove(tree), best)
+		t.Fail()
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	tree := newNimTree()
+	_, stats, _ := tree.Search(context.Background(), &nimBoard{stones: 1000}, &nim{}, Limits{Nodes: 1000})
+	if stats.Reason != StopNodes || stats.Nodes < 1000 || stats.Nodes > 1003 {
+		fmt.Println(stats)
+		t.Fail()
+	}
+
+	tree = newNimTree()
+	_, stats, _ = tree.Search(context.Background(), &nimBoard{stones: 1000}, &nim{}, Limits{Duration: 10 * time.Millisecond})
+	if stats.Reason != StopDuration || stats.Duration > time.Second {
+		fmt.Println(stats)
+		t.Fail()
+	}
+
+	tree = newNimTree()
+	_, stats, _ = tree.Search(context.Background(), &nimBoard{stones: 1000}, &nim{}, Limits{Memory: 1 << 16})
+	if stats.Reason != StopMemory || stats.Memory < 1<<16 {
+		fmt.Println(stats)
+		t.Fail()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tree = newNimTree()
+	_, stats, _ = tree.Search(ctx, &nimBoard{stones: 1000}, &nim{}, Limits{})
+	if stats.Reason != StopCanceled || stats.Simulations != 0 {
+		fmt.Println(stats)
+		t.Fail()
+	}
+}
+
+func TestSearchBestMoveFixed(t *testing.T) {
+	tests := []struct {
+		policy      FinalMove
+		lost        bool
+		simulations int
+		reason      StopReason
+		run         int
+	}{
+		{MaxVisits, false, 39, StopBestMoveFixed, 0},
+		{MaxRobust, false, 39, StopBestMoveFixed, 0},
+		{MaxValue, false, 39, StopSimulations, 39},
+		{SecureChild, false, 39, StopSimulations, 39},
+		{MaxValue, true, 39, StopBestMoveFixed, 0},
+	}
+	for _, test := range tests {
+		tree := newNimTree()
+		tree.nodes = []node{
+			{firstChild: 1, lastChild: 4},
+			{nSims: 50, value: 10},
+			{nSims: 10, value: 8},
+			{nSims: 5, value: 4},
+		}
+		tree.moves = []nimMove{0, 1, 2, 3}
+		if test.lost {
+			tree.nodes[2].value = Loss
+			tree.nodes[3].value = Loss
+		}
+		tree.update(0)
+		tree.SetFinalMove(test.policy)
+		_, stats, _ := tree.Search(context.Background(), &nimBoard{stones: 1000}, &nim{}, Limits{Simulations: test.simulations})
+		if stats.Reason != test.reason || stats.Simulations != test.run {
+			fmt.Println(test, stats)
+			t.Fail()
+		}
+	}
+}
+
+func BenchmarkExpand(b *testing.B) {
+	tree := newNimTree()
+	board := &nimBoard{stones: 1000}
+	b.ResetTimer()
+	for range b.N {
+		tree.Expand(board, &nim{})
+	}
+}
+
+func BenchmarkSearchParallel(b *testing.B) {
+	tree := newNimTree()
+	tree.SetWorkers(4)
+	board := &nimBoard{stones: 1000}
+	b.ResetTimer()
+	tree.Search(context.Background(), board, &nim{}, Limits{Simulations: b.N})
+}
@@ -0,0 +1,117 @@
+package tree
+
+import (
+	"fmt"
+
+	. "monte/common"
+)
+
+// Validate checks the invariants of the node arena: every node but the root
+// is the child of exactly one node, children occupy a contiguous range after
+// their parent, and every expanded node agrees with the values of its children.
+// With transpositions a range of children may be shared by several nodes,
+// which pick up proven values only when the search passes through them, so
+// their values are not checked. Simulations are backed up along the path then,
+// so only proven values are checked for the other nodes.
+func (tree *Tree[game, board, move]) Validate() error {
+	if len(tree.nodes) != len(tree.moves) {
+		return fmt.Errorf("nodes: %d, moves: %d", len(tree.nodes), len(tree.moves))
+	}
+	if len(tree.nodes) == 0 {
+		return fmt.Errorf("no root")
+	}
+
+	parents := make([]int32, len(tree.nodes))
+	for idx := range parents {
+		parents[idx] = -1
+	}
+	shared := map[int32]int{}
+	for idx, node := range tree.nodes {
+		if node.virtualLoss != 0 {
+			return fmt.Errorf("node %d: virtual loss %d", idx, node.virtualLoss)
+		}
+		if node.firstChild == 0 && node.lastChild == 0 {
+			continue
+		}
+		if node.firstChild >= node.lastChild || int(node.lastChild) > len(tree.nodes) {
+			return fmt.Errorf("node %d: children [%d:%d] out of bounds", idx, node.firstChild, node.lastChild)
+		}
+		owner := parents[node.firstChild]
+		if owner != -1 && tree.table != nil && tree.nodes[owner].firstChild == node.firstChild && tree.nodes[owner].lastChild == node.lastChild {
+			shared[node.firstChild]++
+			continue
+		}
+		if node.firstChild <= int32(idx) && tree.table == nil {
+			return fmt.Errorf("node %d: children [%d:%d] before the node", idx, node.firstChild, node.lastChild)
+		}
+		for childIdx := node.firstChild; childIdx < node.lastChild; childIdx++ {
+			if parents[childIdx] != -1 {
+				return fmt.Errorf("node %d: child of %d and %d", childIdx, parents[childIdx], idx)
+			}
+			parents[childIdx] = int32(idx)
+		}
+	}
+	for idx := 1; idx < len(parents); idx++ {
+		if parents[idx] == -1 {
+			return fmt.Errorf("node %d: no parent", idx)
+		}
+	}
+	for idx, node := range tree.nodes {
+		if node.firstChild == 0 && node.lastChild == 0 || shared[node.firstChild] > 0 {
+			continue
+		}
+		if err := tree.validateNode(int32(idx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tree *Tree[game, board, move]) validateNode(idx int32) error {
+	parent := tree.nodes[idx]
+	nSims := int32(0)
+	value, squares := Value(0), Value(0)
+	hasWin, hasDraw, hasUndecided := false, false, false
+	for childIdx := parent.firstChild; childIdx < parent.lastChild; childIdx++ {
+		child := tree.nodes[childIdx]
+		if child.value.IsWin() {
+			hasWin = true
+		} else if child.value.IsDraw() {
+			hasDraw = true
+		} else if !child.value.IsLoss() {
+			hasUndecided = true
+			nSims += child.nSims
+			value += child.value
+			squares += child.squares
+		}
+	}
+	if parent.nSims != nSims && tree.table == nil {
+		return fmt.Errorf("node %d: nSims %d, sum of undecided children %d", idx, parent.nSims, nSims)
+	}
+
+	switch {
+	case hasWin:
+		if !parent.value.IsLoss() {
+			return fmt.Errorf("node %d: value %v with a winning child", idx, parent.value)
+		}
+	case !hasUndecided && hasDraw:
+		if !parent.value.IsDraw() {
+			return fmt.Errorf("node %d: value %v with draws and losses only", idx, parent.value)
+		}
+	case !hasUndecided:
+		if !parent.value.IsWin() {
+			return fmt.Errorf("node %d: value %v with losing children only", idx, parent.value)
+		}
+	case parent.value.IsDecided():
+		return fmt.Errorf("node %d: value %v with undecided children", idx, parent.value)
+	default:
+		value = -value
+		if hasDraw && value < 0 {
+			value = 0
+		}
+		if tree.table == nil && (parent.value != value || parent.squares != squares) {
+			return fmt.Errorf("node %d: value %v squares %v, children sum to %v %v", idx, parent.value, parent.squares, value, squares)
+		}
+	}
+	return nil
+}
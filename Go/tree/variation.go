@@ -0,0 +1,101 @@
+package tree
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Line is a root move with the principal variation that starts with it.
+type Line[move any] struct {
+	Decision[move]
+	PV []move
+}
+
+// PrincipalVariation returns the line the search expects: from the root down
+// to a leaf it follows the child selected by the policy, MaxVisits for the
+// most simulated moves or MaxValue for the best valued ones.
+func (tree *Tree[game, board, move]) PrincipalVariation(policy FinalMove) []move {
+	return tree.variation(0, policy, []move{})
+}
+
+// Lines returns the best n root moves, or all of them for n <= 0, each with
+// its principal variation. The first one is the move BestMove selects with the
+// policy, the others follow ranked by the policy, with the moves that have no
+// simulations and the proven losses last.
+func (tree *Tree[game, board, move]) Lines(n int, policy FinalMove) []Line[move] {
+	root := tree.nodes[0]
+	idxs := make([]int32, 0, root.lastChild-root.firstChild)
+	for idx := root.firstChild; idx < root.lastChild; idx++ {
+		idxs = append(idxs, idx)
+	}
+	slices.SortStableFunc(idxs, func(a, b int32) int {
+		return tree.compare(b, a, policy)
+	})
+	if best := slices.Index(idxs, tree.bestChild(0, policy)); best > 0 {
+		copy(idxs[1:best+1], idxs[:best])
+		idxs[0] = tree.bestChild(0, policy)
+	}
+	if n > 0 && len(idxs) > n {
+		idxs = idxs[:n]
+	}
+
+	lines := make([]Line[move], len(idxs))
+	for i, idx := range idxs {
+		lines[i] = Line[move]{
+			Decision: tree.decision(idx),
+			PV:       tree.variation(idx, policy, []move{tree.moves[idx]}),
+		}
+	}
+	return lines
+}
+
+// variation appends the moves the policy selects below the node to pv.
+func (tree *Tree[game, board, move]) variation(idx int32, policy FinalMove, pv []move) []move {
+	for tree.nodes[idx].firstChild != 0 {
+		idx = tree.bestChild(idx, policy)
+		pv = append(pv, tree.moves[idx])
+	}
+	return pv
+}
+
+// compare ranks two children with the policy like bestChild does.
+func (tree *Tree[game, board, move]) compare(a, b int32, policy FinalMove) int {
+	classA, keyA, tieA := tree.rank(a, policy)
+	classB, keyB, tieB := tree.rank(b, policy)
+	if c := cmp.Compare(classA, classB); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(keyA, keyB); c != 0 {
+		return c
+	}
+	return cmp.Compare(tieA, tieB)
+}
+
+// rank returns the class of a child, from proven losses to proven wins, and
+// the keys that order the children of the same class.
+func (tree *Tree[game, board, move]) rank(idx int32, policy FinalMove) (int, float64, float64) {
+	child := tree.nodes[idx]
+	switch {
+	case child.value.IsWin():
+		return 3, 0, 0
+	case child.value.IsLoss():
+		return 0, 0, 0
+	case child.nSims == 0:
+		return 1, 0, 0
+	}
+	value := 0.0
+	if !child.value.IsDraw() {
+		value = float64(child.value) / float64(child.nSims)
+	}
+	visits := float64(child.nSims)
+	switch policy {
+	case MaxVisits:
+		return 2, visits, 0
+	case MaxRobust:
+		return 2, visits, value
+	case SecureChild:
+		return 2, value - secureFactor/math.Sqrt(visits), 0
+	}
+	return 2, value, 0
+}